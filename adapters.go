@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/context"
+)
+
+// Adapter for github.com/julienschmidt/httprouter
+type httprouterAdapter struct {
+	*httprouter.Router
+}
+
+func (router *httprouterAdapter) Add(method, path string, h Handler) {
+	router.Handle(method, path, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		req := WrapHttpRouterReq(w, r, ps)
+		h.Serve(context.Background(), req)
+	})
+}
+
+// Adapter for pat-like routers (github.com/gorilla/pat, github.com/bmizerany/pat)
+type patRouter interface {
+	Add(meth, pat string, h http.Handler)
+}
+
+type patAdapter struct {
+	r patRouter
+}
+
+func (router patAdapter) Add(method, path string, h Handler) {
+	router.r.Add(method, path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := WrapReq(w, r)
+		h.Serve(context.Background(), req)
+	}))
+}
+
+// RegisterFunc registers a http.Handler for a method and a path.
+// Any router can be activated through a RegisterFunc, i.e.
+//
+//	api.Activate(RegisterFunc(func(method, path string, h http.Handler) {
+//		router.Handle(method, path, h)
+//	}))
+//
+// The path keeps the :name and *name parameters of the endpoint.
+type RegisterFunc func(method, path string, h http.Handler)
+
+// Adapter for RegisterFunc.
+// Since the router is unknown, path parameters are extracted
+// by matching the request path against the endpoint path.
+type registerFuncAdapter struct {
+	register RegisterFunc
+}
+
+func (router registerFuncAdapter) Add(method, path string, h Handler) {
+	router.register(method, path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := new(Params)
+
+		if ps, _, ok := matchPath(path, r.URL.Path); ok && len(ps) > 0 {
+			params.Path = ps
+		}
+
+		h.Serve(context.Background(), NewReq(w, r, params))
+	}))
+}
+
+// RewritePath rewrites the :name and *name segments of an endpoint path
+// for routers using a different syntax, i.e.
+//
+//	RewritePath(path, func(name string) string {
+//		return "{" + name + "}"
+//	}, func(name string) string {
+//		return "*"
+//	})
+//
+// rewrites /users/:id/*path into the chi pattern /users/{id}/*
+func RewritePath(path string, param, catchAll func(name string) string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			segments[i] = param(s[1:])
+		case strings.HasPrefix(s, "*"):
+			segments[i] = catchAll(s[1:])
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isCatchAll reports whether name is the *name parameter of an endpoint path.
+func isCatchAll(path, name string) bool {
+	for _, s := range splitPath(path) {
+		if s == "*"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// PathParams returns the Params of the :name and *name parameters
+// of an endpoint path, for adapters of routers which do not give them
+// in the httprouter form. The value of each parameter is looked up
+// from the router, and the values of *name parameters get a leading "/".
+func PathParams(path string, lookup func(name string, catchAll bool) string) *Params {
+	params := new(Params)
+
+	names := pathParams(path)
+	if len(names) == 0 {
+		return params
+	}
+
+	params.Path = make(Values, len(names))
+	for _, name := range names {
+		catchAll := isCatchAll(path, name)
+		value := lookup(name, catchAll)
+		if catchAll {
+			value = catchAllValue(value)
+		}
+		params.Path[":"+name] = splitValues([]string{value}, ",")
+	}
+	return params
+}
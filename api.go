@@ -1,12 +1,9 @@
 package api
 
 import (
-	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/julienschmidt/httprouter"
 	"golang.org/x/net/context"
 )
 
@@ -22,8 +19,14 @@ func New(prefix string) *API {
 type API struct {
 	Endpoints  []Endpoint
 	Middleware MiddlewareStack
+	Wrappers   WrapperStack
+	Hooks      Hooks // Hooks of every Resource added with AddResource
 	options    map[string][]string
 	Prefix     string
+
+	// OnHookError reports the errors of the after Hooks
+	// of every Resource added with AddResource, see Resource.OnHookError.
+	OnHookError func(context.Context, HookPoint, *Error)
 }
 
 // HandlerFunc
@@ -40,12 +43,18 @@ type Handler interface {
 
 // Router is an interface that helps activating an API
 // to different types of router libraries (pat, httprouter, http, etc.)
+// Adapters for chi, gorilla/mux and echo are registered by importing
+// the chiadapter, muxadapter and echoadapter packages.
+// An API can also be served directly since it implements http.Handler.
 type Router interface {
 	Add(method, path string, handler Handler)
 }
 
 // Add() adds an endpoint to the API
+// after normalizing its metadata.
 func (api *API) Add(e Endpoint) {
+	e.describe()
+
 	// add endpoint
 	api.Endpoints = append(api.Endpoints, e)
 
@@ -65,8 +74,15 @@ func (api *API) Use(mw Middleware) {
 	api.Middleware = append(api.Middleware, mw)
 }
 
+// Append a wrapper to the wrapper stack.
+// Wrappers run around the middleware stack of the API
+// and the endpoint itself.
+func (api *API) Wrap(w Wrapper) {
+	api.Wrappers = append(api.Wrappers, w)
+}
+
 // Activate() registers all endpoints in the api
-// to the provided router
+// to the provided router, wrapped with WrapRouter
 func (api *API) Activate(r interface{}) error {
 	router, err := WrapRouter(r)
 	if err != nil {
@@ -78,70 +94,25 @@ func (api *API) Activate(r interface{}) error {
 	}
 
 	for path, verbs := range api.options {
-		router.Add("OPTIONS", api.Prefix+path, HandlerFunc(func(ctx context.Context, r *Req) {
-			r.Response.Header().Set("Allow", strings.Join(verbs, ","))
-			r.Response.WriteHeader(http.StatusNoContent)
-		}))
+		router.Add("OPTIONS", api.Prefix+path, optionsHandler(verbs))
 	}
 
 	return nil
 }
 
 func (api *API) activateEndpoint(e Endpoint, r Router) {
-	r.Add(e.Method, api.Prefix+e.Path, HandlerFunc(func(ctx context.Context, r *Req) {
-		for _, m := range api.Middleware {
-			c, err := m.Run(ctx, r)
-			if err != nil {
-				er := WrapErr(err, 0)
-				r.Response.WriteHeader(er.HTTPStatus())
-				fmt.Fprintln(r.Response, er.HTTPBody())
-				return
-			}
-			ctx = c
-		}
-
-		e.Serve(ctx, r)
-	}))
-}
-
-// Wrap a router to be used with Activate
-// i.e. api.Activate(WrapRouter(router))
-func WrapRouter(v interface{}) (Router, error) {
-	if r, ok := v.(*httprouter.Router); ok {
-		return &httprouterAdapter{r}, nil
-	}
-
-	if r, ok := v.(patRouter); ok {
-		return &patAdapter{r: r}, nil
-	}
-
-	return nil, errors.New("Cannot wrap unsupported router type")
+	r.Add(e.Method, api.Prefix+e.Path, api.handler(e))
 }
 
-// Adapter for github.com/julienschmidt/httprouter
-type httprouterAdapter struct {
-	*httprouter.Router
+// handler composes the API wrappers and middleware stack around an endpoint.
+func (api *API) handler(e Endpoint) Handler {
+	return api.Wrappers.Then(api.Middleware.Then(e))
 }
 
-func (router *httprouterAdapter) Add(method, path string, h Handler) {
-	router.Handle(method, path, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		req := WrapHttpRouterReq(w, r, ps)
-		h.Serve(context.Background(), req)
+// optionsHandler responds to OPTIONS requests with the allowed verbs.
+func optionsHandler(verbs []string) Handler {
+	return HandlerFunc(func(ctx context.Context, r *Req) {
+		r.Response.Header().Set("Allow", strings.Join(verbs, ","))
+		r.Response.WriteHeader(http.StatusNoContent)
 	})
 }
-
-// Adapter for pat-like routers (github.com/gorilla/pat, github.com/bmizerany/pat)
-type patRouter interface {
-	Add(meth, pat string, h http.Handler)
-}
-
-type patAdapter struct {
-	r patRouter
-}
-
-func (router patAdapter) Add(method, path string, h Handler) {
-	router.r.Add(method, path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		req := WrapReq(w, r)
-		h.Serve(context.Background(), req)
-	}))
-}
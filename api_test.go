@@ -153,6 +153,29 @@ var _ = Describe("api integration", func() {
 		})
 	})
 
+	Context("wrappers", func() {
+		It("runs API wrappers around the middleware stack", func() {
+			router := httprouter.New()
+			api := makeAPI()
+			api.Wrap(WrapperFunc(func(next Handler) Handler {
+				return HandlerFunc(func(ctx context.Context, r *Req) {
+					r.Response.Header().Set("X-Wrapped", "true")
+					next.Serve(ctx, r)
+					Expect(r.ResponseStatus()).To(Equal(200))
+				})
+			}))
+			api.Activate(router)
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/v1/pets/abc", strings.NewReader(`{"name":"simba"}`))
+			router.ServeHTTP(res, req)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Header().Get("X-Wrapped")).To(Equal("true"))
+			Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+		})
+	})
+
 	Context("Gorilla Pat", func() {
 		It("handles a failing middleware", func() {
 			router := pat.New()
@@ -209,4 +232,263 @@ var _ = Describe("api integration", func() {
 			Expect(id).To(Equal("abc"))
 		})
 	})
+
+	Context("net/http ServeMux", func() {
+		It("handles a failing middleware", func() {
+			router := http.NewServeMux()
+			api := makeAPI()
+			api.Activate(router)
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/v1/pets", strings.NewReader(`{"name":"moufassa","id":"abc"}`))
+			router.ServeHTTP(res, req)
+
+			Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+			Expect(res.Code).To(Equal(401))
+		})
+
+		It("works", func() {
+			router := http.NewServeMux()
+			api := makeAPI()
+			api.Activate(router)
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/v1/pets/abc", strings.NewReader(`{"name":"simba"}`))
+			router.ServeHTTP(res, req)
+
+			Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+			Expect(res.Code).To(Equal(200))
+
+			data, err := jason.NewObjectFromReader(res.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			name, err := data.GetString("name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("simba"))
+
+			id, err := data.GetString("id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("abc"))
+		})
+
+		It("answers OPTIONS requests", func() {
+			router := http.NewServeMux()
+			api := makeAPI()
+			api.Activate(router)
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("OPTIONS", "/v1/pets/abc", nil)
+			router.ServeHTTP(res, req)
+
+			Expect(res.Code).To(Equal(http.StatusNoContent))
+			Expect(res.Header().Get("Allow")).To(Equal("OPTIONS,PUT"))
+		})
+
+		It("converts paths to ServeMux patterns", func() {
+			Expect(serveMuxPattern("/v1/pets/:id")).To(Equal("/v1/pets/{id}"))
+			Expect(serveMuxPattern("/v1/files/*path")).To(Equal("/v1/files/{path...}"))
+			Expect(serveMuxPattern("/v1/pets/")).To(Equal("/v1/pets/{$}"))
+		})
+	})
+
+	Context("standalone", func() {
+		It("handles a failing middleware", func() {
+			api := makeAPI()
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/v1/pets", strings.NewReader(`{"name":"moufassa","id":"abc"}`))
+			api.ServeHTTP(res, req)
+
+			Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+			Expect(res.Code).To(Equal(401))
+		})
+
+		It("works", func() {
+			api := makeAPI()
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/v1/pets/abc", strings.NewReader(`{"name":"simba"}`))
+			api.ServeHTTP(res, req)
+
+			Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+			Expect(res.Code).To(Equal(200))
+
+			data, err := jason.NewObjectFromReader(res.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			id, err := data.GetString("id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("abc"))
+		})
+
+		It("prefers static segments over parameters", func() {
+			api := New("/v1")
+			for _, path := range []string{"/pets/:id", "/pets/search"} {
+				path := path
+				api.Add(Endpoint{
+					Method: "GET",
+					Path:   path,
+					Implementation: func(ctx context.Context, r *Req) {
+						r.Response.Header().Set("X-Path", path)
+						r.Response.Header().Set("X-Id", r.Params.Get(":id"))
+						r.NoContent(http.StatusNoContent)
+					},
+				})
+			}
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/v1/pets/search", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Header().Get("X-Path")).To(Equal("/pets/search"))
+
+			res = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", "/v1/pets/abc", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Header().Get("X-Path")).To(Equal("/pets/:id"))
+			Expect(res.Header().Get("X-Id")).To(Equal("abc"))
+		})
+
+		It("responds with 404 and 405 errors", func() {
+			api := makeAPI()
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/v1/unknown", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Code).To(Equal(http.StatusNotFound))
+
+			res = httptest.NewRecorder()
+			req, _ = http.NewRequest("DELETE", "/v1/pets/abc", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(res.Header().Get("Allow")).To(Equal("OPTIONS,PUT"))
+
+			res = httptest.NewRecorder()
+			req, _ = http.NewRequest("OPTIONS", "/v1/pets", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Code).To(Equal(http.StatusNoContent))
+			Expect(res.Header().Get("Allow")).To(Equal("OPTIONS,POST"))
+		})
+
+		It("matches the most specific path of the method", func() {
+			api := New("")
+			for _, e := range []struct{ method, path string }{{"GET", "/pets/:id"}, {"POST", "/pets/search"}} {
+				path := e.path
+				api.Add(Endpoint{
+					Method: e.method,
+					Path:   path,
+					Implementation: func(ctx context.Context, r *Req) {
+						r.Response.Header().Set("X-Path", path)
+						r.NoContent(http.StatusNoContent)
+					},
+				})
+			}
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/pets/search", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Code).To(Equal(http.StatusNoContent))
+			Expect(res.Header().Get("X-Path")).To(Equal("/pets/:id"))
+
+			res = httptest.NewRecorder()
+			req, _ = http.NewRequest("DELETE", "/pets/search", nil)
+			api.ServeHTTP(res, req)
+			Expect(res.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(res.Header().Get("Allow")).To(Equal("OPTIONS,GET,POST"))
+		})
+	})
+
+	routers := map[string]func() http.Handler{
+		"RegisterFunc": func() http.Handler {
+			router := pat.New()
+			makeAPI().Activate(func(method, path string, h http.Handler) {
+				router.Add(method, path, h)
+			})
+			return router
+		},
+	}
+
+	for name, newRouter := range routers {
+		newRouter := newRouter
+
+		Context(name, func() {
+			It("handles a failing middleware", func() {
+				router := newRouter()
+
+				res := httptest.NewRecorder()
+				req, _ := http.NewRequest("POST", "/v1/pets", strings.NewReader(`{"name":"moufassa","id":"abc"}`))
+				router.ServeHTTP(res, req)
+
+				Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+				Expect(res.Code).To(Equal(401))
+			})
+
+			It("works", func() {
+				router := newRouter()
+
+				res := httptest.NewRecorder()
+				req, _ := http.NewRequest("PUT", "/v1/pets/abc", strings.NewReader(`{"name":"simba"}`))
+				router.ServeHTTP(res, req)
+
+				Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+				Expect(res.Code).To(Equal(200))
+
+				data, err := jason.NewObjectFromReader(res.Body)
+				Expect(err).ToNot(HaveOccurred())
+
+				name, err := data.GetString("name")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(name).To(Equal("simba"))
+
+				id, err := data.GetString("id")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(id).To(Equal("abc"))
+			})
+		})
+	}
+
+	It("gives every router the httprouter value of catch-all parameters", func() {
+		newAPI := func() *API {
+			api := New("/v1")
+			api.Add(Endpoint{
+				Method: "GET",
+				Path:   "/files/*path",
+				Implementation: func(ctx context.Context, r *Req) {
+					r.Response.Header().Set("X-Path", r.Params.Get(":path"))
+					r.NoContent(http.StatusNoContent)
+				},
+			})
+			return api
+		}
+
+		handlers := map[string]func() http.Handler{
+			"standalone": func() http.Handler { return newAPI() },
+		}
+		for name, router := range map[string]func() http.Handler{
+			"httprouter": func() http.Handler { return httprouter.New() },
+			"ServeMux":   func() http.Handler { return http.NewServeMux() },
+		} {
+			router := router
+			handlers[name] = func() http.Handler {
+				r := router()
+				Expect(newAPI().Activate(r)).To(Succeed())
+				return r
+			}
+		}
+		handlers["RegisterFunc"] = func() http.Handler {
+			router := http.NewServeMux()
+			newAPI().Activate(RegisterFunc(func(method, path string, h http.Handler) {
+				router.Handle(method+" "+serveMuxPattern(path), h)
+			}))
+			return router
+		}
+
+		for name, newHandler := range handlers {
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/v1/files/docs/readme.md", nil)
+			newHandler().ServeHTTP(res, req)
+
+			Expect(res.Code).To(Equal(http.StatusNoContent), name)
+			Expect(res.Header().Get("X-Path")).To(Equal("/docs/readme.md"), name)
+		}
+	})
 })
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Bind populates the struct pointed to by v from the request.
+// The body, if any, is decoded first with Decode, then the tagged fields
+// are set from the parameters and headers.
+// Values which cannot be parsed are reported as Errors,
+// with one Error per failing field.
+// Once bound, the struct is checked with Validate.
+//
+// Supported tags:
+//   - "query" name of a parameter of the query string
+//   - "path" name of a parameter of the url path, with or without its leading colon
+//   - "form" name of a parameter of the request body
+//   - "header" name of a header
+//   - "default" value used when the parameter is missing
+//
+// Slices, and pointers to slices, get every value of a parameter,
+// comma separated values of the query string and path being split as in Params.
+func (r *Req) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("api: Bind expects a pointer to a struct")
+	}
+
+	if r.Params == nil {
+		r.Params = new(Params)
+	}
+	if r.Params.Values == nil {
+		if err := r.ParseParams(); err != nil {
+			return WrapErr(err, http.StatusBadRequest)
+		}
+	}
+
+	// Form bodies are bound with form tags.
+	if r.ContentType != "application/x-www-form-urlencoded" && r.ContentType != "multipart/form-data" {
+		body, err := r.readBody()
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := r.Decode(v); err != nil {
+				if _, ok := err.(HTTPError); ok {
+					return err
+				}
+				return &Error{
+					Status: strconv.Itoa(http.StatusBadRequest),
+					Code:   "invalid_body",
+					Title:  "Invalid body",
+					Detail: err.Error(),
+				}
+			}
+		}
+	}
+
+	errs := Errors{}
+	r.bindFields(rv.Elem(), &errs)
+
+	if len(errs.Err) > 0 {
+		return errs
+	}
+	return Validate(v)
+}
+
+func (r *Req) bindFields(v reflect.Value, errs *Errors) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			r.bindFields(fv, errs)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		values, source := r.bindValues(f)
+		if source == nil {
+			continue
+		}
+
+		if len(values) == 0 {
+			def, ok := f.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			values = []string{def}
+			if t := fv.Type(); t.Kind() == reflect.Slice || t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice {
+				values = strings.Split(def, ",")
+			}
+		}
+
+		if err := setValues(fv, values); err != nil {
+			errs.Add(&Error{
+				Status: strconv.Itoa(http.StatusBadRequest),
+				Code:   "invalid_type",
+				Title:  "Invalid parameter",
+				Detail: fmt.Sprintf("%q %s", source.Parameter+source.Header, err.Error()),
+				Path:   source.Parameter + source.Header,
+				Source: source,
+			})
+		}
+	}
+}
+
+// bindValues returns the values of the parameter a field is tagged with,
+// and where they come from.
+func (r *Req) bindValues(f reflect.StructField) ([]string, *ErrorSource) {
+	if name := f.Tag.Get("query"); name != "" {
+		return splitValues(r.Params.Query[name], ","), &ErrorSource{Parameter: name}
+	}
+
+	if name := f.Tag.Get("path"); name != "" {
+		name = strings.TrimPrefix(name, ":")
+		return splitValues(r.Params.Path[":"+name], ","), &ErrorSource{Parameter: name}
+	}
+
+	if name := f.Tag.Get("form"); name != "" {
+		return r.Params.Form[name], &ErrorSource{Parameter: name}
+	}
+
+	if name := f.Tag.Get("header"); name != "" {
+		return r.Request.Header[http.CanonicalHeaderKey(name)], &ErrorSource{Header: name}
+	}
+
+	return nil, nil
+}
+
+// setValues sets a value from its string representations.
+func setValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Slice {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValues(v.Elem(), values)
+	}
+
+	if v.Kind() == reflect.Slice && !isTextUnmarshaler(v) {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	return setValue(v, values[0])
+}
+
+// setValue sets a value from its string representation.
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+
+	if isTextUnmarshaler(v) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			if v.Type() == timeType {
+				return errors.New("must be a RFC 3339 date")
+			}
+			return fmt.Errorf("is invalid: %v", err)
+		}
+		return nil
+	}
+
+	if err := checkType(v.Type(), s); err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, _ := strconv.ParseBool(s)
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, _ := strconv.ParseInt(s, 10, v.Type().Bits())
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, _ := strconv.ParseUint(s, 10, v.Type().Bits())
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, _ := strconv.ParseFloat(s, v.Type().Bits())
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("cannot be bound to a %s", v.Type())
+	}
+
+	return nil
+}
+
+func isTextUnmarshaler(v reflect.Value) bool {
+	return v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textUnmarshalerType)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type pagination struct {
+	Limit  int `query:"limit" default:"20"`
+	Offset int `query:"offset"`
+}
+
+type petQuery struct {
+	pagination
+
+	ID     string     `path:"id"`
+	Tags   []string   `query:"tags"`
+	Ages   []int      `query:"ages"`
+	Since  *time.Time `query:"since"`
+	IP     net.IP     `header:"X-Forwarded-For"`
+	Token  string     `header:"X-Token"`
+	Fields []string   `query:"fields" default:"id,name"`
+	Name   string     `json:"name"`
+}
+
+func bindReq(method, url, contentType, body string) *Req {
+	r, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r.Header.Set("X-Token", "secret")
+	r.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	req := WrapHttpRouterReq(httptest.NewRecorder(), r, nil)
+	req.Params.Path = Values{":id": {"abc"}}
+	return req
+}
+
+var _ = Describe("Bind", func() {
+	It("binds parameters, headers and body into a struct", func() {
+		req := bindReq("PUT", "/pets/abc?tags=a,b&tags=c&ages=1,2&since=2016-01-02T15:04:05Z", "application/json", `{"name":"simba"}`)
+
+		q := petQuery{}
+		Expect(req.Bind(&q)).To(Succeed())
+
+		Expect(q.ID).To(Equal("abc"))
+		Expect(q.Limit).To(Equal(20))
+		Expect(q.Offset).To(Equal(0))
+		Expect(q.Tags).To(Equal([]string{"a", "b", "c"}))
+		Expect(q.Ages).To(Equal([]int{1, 2}))
+		Expect(q.Since.Year()).To(Equal(2016))
+		Expect(q.IP.String()).To(Equal("10.0.0.1"))
+		Expect(q.Token).To(Equal("secret"))
+		Expect(q.Fields).To(Equal([]string{"id", "name"}))
+		Expect(q.Name).To(Equal("simba"))
+	})
+
+	It("binds form values", func() {
+		form := struct {
+			Name string `form:"name"`
+			Age  uint   `form:"age"`
+		}{}
+
+		req := bindReq("POST", "/pets", "application/x-www-form-urlencoded", "name=simba&age=3")
+		Expect(req.Bind(&form)).To(Succeed())
+		Expect(form.Name).To(Equal("simba"))
+		Expect(form.Age).To(Equal(uint(3)))
+	})
+
+	It("aggregates errors", func() {
+		req := bindReq("GET", "/pets/abc?limit=ten&ages=1,b&since=yesterday", "", "")
+
+		q := petQuery{}
+		err := req.Bind(&q)
+		Expect(err).To(HaveOccurred())
+
+		errs, ok := err.(Errors)
+		Expect(ok).To(BeTrue())
+		Expect(errs.Err).To(HaveLen(3))
+		Expect(errs.HTTPStatus()).To(Equal(http.StatusBadRequest))
+		Expect(errs.Err[0].Path).To(Equal("limit"))
+		Expect(errs.Err[0].Source.Parameter).To(Equal("limit"))
+		Expect(errs.Err[1].Path).To(Equal("ages"))
+		Expect(errs.Err[2].Detail).To(ContainSubstring("RFC 3339"))
+	})
+
+	It("binds pointers to slices and reports path parameters by name", func() {
+		q := struct {
+			Colors *[]string `query:"colors"`
+			Sizes  *[]int    `query:"sizes" default:"1,2"`
+			Owner  *[]string `query:"owner"`
+		}{}
+
+		req := bindReq("GET", "/pets/abc?colors=red,blue", "", "")
+		Expect(req.Bind(&q)).To(Succeed())
+		Expect(*q.Colors).To(Equal([]string{"red", "blue"}))
+		Expect(*q.Sizes).To(Equal([]int{1, 2}))
+		Expect(q.Owner).To(BeNil())
+
+		p := struct {
+			ID int `path:":id"`
+		}{}
+		errs := req.Bind(&p).(Errors)
+		Expect(errs.Err[0].Source.Parameter).To(Equal("id"))
+		Expect(errs.Err[0].Path).To(Equal("id"))
+	})
+
+	It("rejects anything but a pointer to a struct", func() {
+		req := bindReq("GET", "/pets", "", "")
+		Expect(req.Bind(petQuery{})).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// BulkDataSource is implemented by DataSources writing several models at once.
+// Their methods return a context with a Result whose Data is
+// a []BulkItem, one per model or id, in order.
+// Resource loops over the methods of other DataSources instead.
+type BulkDataSource interface {
+	// CreateMany creates the models of the context, see WithModels.
+	CreateMany(context.Context) (context.Context, error)
+
+	// UpdateMany updates the models of the context, see WithModels,
+	// each identified by its own id.
+	UpdateMany(context.Context) (context.Context, error)
+
+	// DeleteMany deletes the models of the ids of the Query.
+	DeleteMany(context.Context) (context.Context, error)
+}
+
+// BulkItem is the outcome of a bulk operation for one model.
+type BulkItem struct {
+	ID     string      `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// NewBulkItem returns the BulkItem of a failed operation.
+func NewBulkItem(id string, err error) BulkItem {
+	item := BulkItem{ID: id}
+
+	switch e := err.(type) {
+	case Errors:
+		item.Errors = e.Err
+		item.Status = e.HTTPStatus()
+	default:
+		apiErr := WrapErr(err, http.StatusInternalServerError)
+		item.Errors = []*Error{apiErr}
+		item.Status = apiErr.HTTPStatus()
+	}
+	return item
+}
+
+// Failed reports whether the operation failed.
+func (i BulkItem) Failed() bool {
+	return i.Status >= 400
+}
+
+// BulkResponse is the body of the response to a bulk operation.
+type BulkResponse struct {
+	Results []BulkItem `json:"results"`
+}
+
+type modelsKey struct{}
+
+// WithModels returns a context holding the models decoded
+// from a bulk request, to be created or updated.
+func WithModels(ctx context.Context, models []interface{}) context.Context {
+	return context.WithValue(ctx, modelsKey{}, models)
+}
+
+// ModelsFrom returns the models held by a context, if any.
+func ModelsFrom(ctx context.Context) []interface{} {
+	models, _ := ctx.Value(modelsKey{}).([]interface{})
+	return models
+}
+
+// CreateMany validates and creates the models of the context, see WithModels.
+func (r *Resource) CreateMany(ctx context.Context) (context.Context, error) {
+	return r.writeMany(ctx, BeforeCreate, AfterCreate, func(ctx context.Context, model interface{}) BulkItem {
+		c, err := r.Source.Create(WithModel(ctx, model))
+		if err == nil {
+			c, err = r.Source.FindOne(c)
+		}
+		if err != nil {
+			return NewBulkItem("", err)
+		}
+		return BulkItem{ID: IDFrom(c), Status: http.StatusCreated, Data: ResultFrom(c).Data}
+	}, func(bulk BulkDataSource) func(context.Context) (context.Context, error) {
+		return bulk.CreateMany
+	})
+}
+
+func (r *Resource) HandleCreateMany(ctx context.Context, rp RequestParser) (context.Context, error) {
+	c, err := rp.ParseRequest(ctx, r.Req)
+	if err != nil {
+		return c, err
+	}
+
+	return r.CreateMany(c)
+}
+
+// UpdateMany validates and updates the models of the context, see WithModels.
+// Each model is identified by its own id, see ModelID.
+// With an If-Match header, each model must match one of its ETags,
+// and they are updated one by one.
+func (r *Resource) UpdateMany(ctx context.Context) (context.Context, error) {
+	ifMatch, err := r.ifMatch()
+	if err != nil {
+		return ctx, err
+	}
+
+	many := func(bulk BulkDataSource) func(context.Context) (context.Context, error) {
+		return bulk.UpdateMany
+	}
+	if ifMatch != "" {
+		many = nil
+	}
+
+	return r.writeMany(ctx, BeforeUpdate, AfterUpdate, func(ctx context.Context, model interface{}) BulkItem {
+		id := ModelID(model)
+		c, err := r.update(WithModel(WithID(ctx, id), model), ifMatch)
+		if err == nil {
+			c, err = r.Source.FindOne(c)
+		}
+		if err != nil {
+			return NewBulkItem(id, err)
+		}
+		return BulkItem{ID: id, Status: http.StatusOK, Data: ResultFrom(c).Data}
+	}, many)
+}
+
+func (r *Resource) HandleUpdateMany(ctx context.Context, rp RequestParser) (context.Context, error) {
+	c, err := rp.ParseRequest(ctx, r.Req)
+	if err != nil {
+		return c, err
+	}
+
+	return r.UpdateMany(c)
+}
+
+// DeleteMany deletes the models of the ids of the Query.
+// In SoftDelete mode, with delete Hooks, or with an If-Match header
+// which each model must match, they are deleted one by one instead.
+func (r *Resource) DeleteMany(ctx context.Context) (context.Context, error) {
+	ifMatch, err := r.ifMatch()
+	if err != nil {
+		return ctx, err
+	}
+
+	hooked := len(r.Hooks[BeforeDelete]) > 0 || len(r.Hooks[AfterDelete]) > 0
+	if bulk, ok := r.Source.(BulkDataSource); ok && !r.SoftDelete && !hooked && ifMatch == "" {
+		return bulk.DeleteMany(ctx)
+	}
+
+	ids := QueryFrom(ctx).IDs
+	items := make([]BulkItem, len(ids))
+	for i, id := range ids {
+		if _, err := r.delete(WithID(ctx, id), ifMatch); err != nil {
+			items[i] = NewBulkItem(id, err)
+			continue
+		}
+		items[i] = BulkItem{ID: id, Status: http.StatusNoContent}
+	}
+
+	return WithResult(ctx, Result{Data: items}), nil
+}
+
+func (r *Resource) HandleDeleteMany(ctx context.Context, rp RequestParser) (context.Context, error) {
+	c, err := rp.ParseRequest(ctx, r.Req)
+	if err != nil {
+		return c, err
+	}
+
+	return r.DeleteMany(c)
+}
+
+// writeMany validates the models of the context and calls their before Hooks,
+// writes the valid ones with a BulkDataSource and many, if not nil,
+// or else one by one with write,
+// and calls the after Hooks of the models written.
+func (r *Resource) writeMany(ctx context.Context, before, after HookPoint, write func(context.Context, interface{}) BulkItem, many func(BulkDataSource) func(context.Context) (context.Context, error)) (context.Context, error) {
+	models := ModelsFrom(ctx)
+	items := make([]BulkItem, len(models))
+
+	var (
+		valid   []interface{}
+		indexes []int
+	)
+	for i, model := range models {
+		err := Validate(model)
+		if err == nil {
+			err = r.hook(ctx, before, model)
+		}
+		if err != nil {
+			items[i] = NewBulkItem(ModelID(model), err)
+			continue
+		}
+		valid = append(valid, model)
+		indexes = append(indexes, i)
+	}
+
+	if bulk, ok := r.Source.(BulkDataSource); ok && many != nil {
+		c, err := many(bulk)(WithModels(ctx, valid))
+		if err != nil {
+			return c, err
+		}
+
+		written, _ := ResultFrom(c).Data.([]BulkItem)
+		for j, i := range indexes {
+			if j < len(written) {
+				items[i] = r.hookItem(c, after, written[j])
+			}
+		}
+		return WithResult(c, Result{Data: items}), nil
+	}
+
+	for j, i := range indexes {
+		items[i] = r.hookItem(ctx, after, write(ctx, valid[j]))
+	}
+	return WithResult(ctx, Result{Data: items}), nil
+}
+
+// hookItem calls the after Hooks of a HookPoint with the model of a BulkItem
+// which did not fail.
+func (r *Resource) hookItem(ctx context.Context, point HookPoint, item BulkItem) BulkItem {
+	if !item.Failed() {
+		r.after(ctx, point, item.Data)
+	}
+	return item
+}
+
+// ModelID returns the id of a model: its field tagged with
+// `jsonapi:"primary,type"`, or else its "id" JSON field or ID field.
+func ModelID(model interface{}) string {
+	v := indirect(reflect.ValueOf(model))
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if idx := idIndex(v.Type()); idx != nil {
+			if id := indirect(v.FieldByIndex(idx)); id.IsValid() && !isZero(id) {
+				return valueString(id)
+			}
+		}
+	case reflect.Map:
+		if id := indirect(v.MapIndex(reflect.ValueOf("id"))); id.IsValid() {
+			return valueString(id)
+		}
+	}
+	return ""
+}
+
+// idIndex returns the index of the id field of a struct type, or nil.
+func idIndex(t reflect.Type) []int {
+	var byJSON, byName []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if strings.HasPrefix(f.Tag.Get("jsonapi"), "primary") {
+			return f.Index
+		}
+		if name, _ := parseJSONTag(f.Tag.Get("json")); name == "id" {
+			byJSON = f.Index
+		}
+		if f.Name == "ID" {
+			byName = f.Index
+		}
+	}
+
+	if byJSON != nil {
+		return byJSON
+	}
+	return byName
+}
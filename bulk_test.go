@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+// bulkPetSource creates pets at once, and counts its calls.
+type bulkPetSource struct {
+	*MemorySource
+	calls int
+}
+
+func (s *bulkPetSource) CreateMany(ctx context.Context) (context.Context, error) {
+	s.calls++
+
+	models := ModelsFrom(ctx)
+	items := make([]BulkItem, len(models))
+	for i, model := range models {
+		c, err := s.Create(WithModel(ctx, model))
+		if err != nil {
+			items[i] = NewBulkItem("", err)
+			continue
+		}
+		items[i] = BulkItem{ID: IDFrom(c), Status: http.StatusCreated, Data: model}
+	}
+	return WithResult(ctx, Result{Data: items}), nil
+}
+
+func (s *bulkPetSource) UpdateMany(ctx context.Context) (context.Context, error) {
+	return ctx, NewError(http.StatusNotImplemented, "Not implemented")
+}
+
+func (s *bulkPetSource) DeleteMany(ctx context.Context) (context.Context, error) {
+	return ctx, NewError(http.StatusNotImplemented, "Not implemented")
+}
+
+// limitedPetSource deletes at most a page of pets at once.
+type limitedPetSource struct {
+	*bulkPetSource
+}
+
+func (s limitedPetSource) DeleteMany(ctx context.Context) (context.Context, error) {
+	q := QueryFrom(ctx)
+	ids := q.IDs
+	if q.Page.Limit > 0 && q.Page.Limit < len(ids) {
+		ids = ids[:q.Page.Limit]
+	}
+
+	items := make([]BulkItem, len(ids))
+	for i, id := range ids {
+		if _, err := s.Delete(WithID(ctx, id)); err != nil {
+			items[i] = NewBulkItem(id, err)
+			continue
+		}
+		items[i] = BulkItem{ID: id, Status: http.StatusNoContent}
+	}
+	return WithResult(ctx, Result{Data: items}), nil
+}
+
+var _ = Describe("Bulk actions", func() {
+	var api *API
+
+	BeforeEach(func() {
+		api = New("")
+		api.AddResource("pets", NewMemorySource(crudPet{}), ResourceModel(crudPet{}), Enable(ActionBulkDelete))
+	})
+
+	It("loops over a DataSource", func() {
+		w := serve(api, "POST", "/pets", ` [{"name":"simba"},{"name":"nala"}]`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Body.String()).To(MatchJSON(`{"results":[
+			{"id":"1","status":201,"data":{"id":"1","name":"simba"}},
+			{"id":"2","status":201,"data":{"id":"2","name":"nala"}}
+		]}`))
+
+		w = serve(api, "PATCH", "/pets", `[{"id":"1","name":"kiara"},{"id":"2","name":"kovu"}]`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"name":"kovu"`))
+
+		w = serve(api, "DELETE", "/pets?id=1,2", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"results":[{"id":"1","status":204},{"id":"2","status":204}]}`))
+
+		Expect(serve(api, "GET", "/pets/1", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("still creates single models", func() {
+		w := serve(api, "POST", "/pets", `{"name":"simba"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/pets/1"))
+	})
+
+	It("reports failed models with a multi-status", func() {
+		serve(api, "POST", "/pets", `{"name":"simba"}`)
+
+		w := serve(api, "POST", "/pets", `[{"name":"nala"},{}]`)
+		Expect(w.Code).To(Equal(http.StatusMultiStatus))
+
+		var body BulkResponse
+		Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+		Expect(body.Results).To(HaveLen(2))
+		Expect(body.Results[0].Status).To(Equal(http.StatusCreated))
+		Expect(body.Results[1].Status).To(Equal(http.StatusUnprocessableEntity))
+		Expect(body.Results[1].Errors).ToNot(BeEmpty())
+
+		w = serve(api, "PATCH", "/pets", `[{"id":"1","name":"kiara"},{"id":"9","name":"kovu"}]`)
+		Expect(w.Code).To(Equal(http.StatusMultiStatus))
+		Expect(w.Body.String()).To(ContainSubstring(`"status":404`))
+
+		w = serve(api, "DELETE", "/pets?id=1,9", "")
+		Expect(w.Code).To(Equal(http.StatusMultiStatus))
+		Expect(w.Body.String()).To(ContainSubstring(`{"id":"1","status":204}`))
+	})
+
+	It("only deletes in bulk once enabled", func() {
+		api = New("")
+		api.AddResource("pets", NewMemorySource(crudPet{}), ResourceModel(crudPet{}))
+
+		serve(api, "POST", "/pets", `{"name":"simba"}`)
+		Expect(serve(api, "DELETE", "/pets?id=1", "").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(serve(api, "GET", "/pets/1", "").Code).To(Equal(http.StatusOK))
+	})
+
+	It("requires ids to delete", func() {
+		w := serve(api, "DELETE", "/pets", "")
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(w.Body.String()).To(ContainSubstring(`"parameter":"id"`))
+	})
+
+	It("deletes more models than the maximum page limit", func() {
+		source := limitedPetSource{&bulkPetSource{MemorySource: NewMemorySource(crudPet{})}}
+		api = New("")
+		api.AddResource("pets", source, ResourceModel(crudPet{}), Enable(ActionBulkDelete), WithQueryRules(QueryRules{MaxLimit: 1}))
+
+		Expect(serve(api, "POST", "/pets", `[{"name":"simba"},{"name":"nala"}]`).Code).To(Equal(http.StatusCreated))
+
+		w := serve(api, "DELETE", "/pets?id=1,2", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"results":[{"id":"1","status":204},{"id":"2","status":204}]}`))
+		Expect(serve(api, "GET", "/pets/2", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("rejects malformed bodies", func() {
+		Expect(serve(api, "POST", "/pets", `[{"name":1}]`).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("uses a BulkDataSource", func() {
+		source := &bulkPetSource{MemorySource: NewMemorySource(crudPet{})}
+		api = New("")
+		api.AddResource("pets", source, ResourceModel(crudPet{}))
+
+		w := serve(api, "POST", "/pets", `[{"name":"simba"},{},{"name":"nala"}]`)
+		Expect(w.Code).To(Equal(http.StatusMultiStatus))
+		Expect(source.calls).To(Equal(1))
+
+		var body BulkResponse
+		Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+		Expect(body.Results[0].ID).To(Equal("1"))
+		Expect(body.Results[1].Status).To(Equal(http.StatusUnprocessableEntity))
+		Expect(body.Results[2].ID).To(Equal("2"))
+	})
+})
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ETagMarshaller is implemented by ResponseMarshallers providing
+// the ETag of their body, quoted. An empty ETag is computed by Send.
+type ETagMarshaller interface {
+	ETag(context.Context) string
+}
+
+// LastModifiedMarshaller is implemented by ResponseMarshallers providing
+// the modification time of their body. A zero time is left out.
+type LastModifiedMarshaller interface {
+	LastModified(context.Context) time.Time
+}
+
+// setValidators sets the ETag, Last-Modified and Cache-Control headers
+// of a successful response. ETags are computed from the model of the body,
+// or else from the encoded body of GET and HEAD responses.
+func setValidators(ctx context.Context, req *Req, rm ResponseMarshaller, body interface{}, data []byte) {
+	header := req.Response.Header()
+
+	etag := ""
+	if m, ok := rm.(ETagMarshaller); ok {
+		etag = m.ETag(ctx)
+	}
+	if etag == "" {
+		etag = etagOf(body)
+	}
+	if etag == "" && data != nil && isSafeMethod(req.Request.Method) {
+		sum := sha256.Sum256(data)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+
+	if m, ok := rm.(LastModifiedMarshaller); ok {
+		if t := m.LastModified(ctx); !t.IsZero() {
+			header.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	if req.cacheControl != "" {
+		header.Set("Cache-Control", req.cacheControl)
+	}
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since headers
+// of a GET or HEAD request against the validators of the response, see RFC 7232.
+func notModified(req *Req) bool {
+	if !isSafeMethod(req.Request.Method) {
+		return false
+	}
+
+	header := req.Response.Header()
+	if inm := req.Request.Header.Get("If-None-Match"); inm != "" {
+		etag := header.Get("ETag")
+		return etag != "" && matchWeakETag(inm, etag)
+	}
+
+	ims, err := http.ParseTime(req.Request.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ims)
+}
+
+// matchWeakETag reports whether an If-None-Match header matches an ETag,
+// with the weak comparison of RFC 7232.
+func matchWeakETag(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD"
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type cachedMarshaller struct {
+	marshaller
+	etag         string
+	lastModified time.Time
+}
+
+func (m cachedMarshaller) ETag(ctx context.Context) string            { return m.etag }
+func (m cachedMarshaller) LastModified(ctx context.Context) time.Time { return m.lastModified }
+
+var _ = Describe("Conditional requests", func() {
+	modified := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	send := func(method string, rm ResponseMarshaller, headers map[string]string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(method, "/pets/1", nil)
+		for key, val := range headers {
+			r.Header.Set(key, val)
+		}
+		w := httptest.NewRecorder()
+		req := WrapReq(w, r)
+		req.cacheControl = "private, max-age=60"
+		Expect(Send(context.Background(), req, rm)).To(Succeed())
+		return w
+	}
+
+	It("sends validators and Cache-Control", func() {
+		w := send("GET", cachedMarshaller{marshaller{body: pet{ID: "1"}, status: 200}, `"v1"`, modified}, nil)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("ETag")).To(Equal(`"v1"`))
+		Expect(w.Header().Get("Last-Modified")).To(Equal("Wed, 01 Jan 2020 12:00:00 GMT"))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("private, max-age=60"))
+
+		w = send("GET", marshaller{body: []pet{{ID: "1"}}, status: 200}, nil)
+		Expect(w.Header().Get("ETag")).To(HaveLen(34))
+
+		w = send("POST", marshaller{body: []pet{{ID: "1"}}, status: 201}, nil)
+		Expect(w.Header().Get("ETag")).To(BeEmpty())
+
+		w = send("GET", marshaller{body: NewError(404, "Not found"), status: 404}, nil)
+		Expect(w.Header().Get("Cache-Control")).To(BeEmpty())
+	})
+
+	It("responds 304 when the ETag matches If-None-Match", func() {
+		rm := cachedMarshaller{marshaller{body: pet{ID: "1"}, status: 200}, `"v1"`, modified}
+
+		w := send("GET", rm, map[string]string{"If-None-Match": `"v0", W/"v1"`})
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+		Expect(w.Body.Len()).To(BeZero())
+		Expect(w.Header().Get("ETag")).To(Equal(`"v1"`))
+		Expect(w.Header().Get("Content-Type")).To(BeEmpty())
+
+		Expect(send("GET", rm, map[string]string{"If-None-Match": `*`}).Code).To(Equal(http.StatusNotModified))
+		Expect(send("GET", rm, map[string]string{"If-None-Match": `"v0"`}).Code).To(Equal(http.StatusOK))
+
+		// If-None-Match takes precedence over If-Modified-Since.
+		w = send("GET", rm, map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": modified.Format(http.TimeFormat)})
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		Expect(send("PUT", rm, map[string]string{"If-None-Match": `"v1"`}).Code).To(Equal(http.StatusOK))
+	})
+
+	It("responds 304 when not modified since If-Modified-Since", func() {
+		rm := cachedMarshaller{marshaller{body: pet{ID: "1"}, status: 200}, `"v1"`, modified}
+
+		Expect(send("GET", rm, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}).Code).To(Equal(http.StatusNotModified))
+		Expect(send("HEAD", rm, map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}).Code).To(Equal(http.StatusNotModified))
+		Expect(send("GET", rm, map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}).Code).To(Equal(http.StatusOK))
+		Expect(send("GET", rm, map[string]string{"If-Modified-Since": "yesterday"}).Code).To(Equal(http.StatusOK))
+	})
+
+	It("works with AddResource", func() {
+		api := New("")
+		source := NewMemorySource(crudPet{})
+		source.Create(WithModel(context.Background(), &crudPet{Name: "simba"}))
+		api.AddResource("pets", source, WithCacheControl(ActionRead, "max-age=30"))
+
+		r, _ := http.NewRequest("GET", "/pets/1", nil)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		Expect(w.Header().Get("Cache-Control")).To(Equal("max-age=30"))
+
+		r, _ = http.NewRequest("GET", "/pets/1", nil)
+		r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+		w = httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+
+		r, _ = http.NewRequest("GET", "/pets", nil)
+		w = httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		Expect(w.Header().Get("Cache-Control")).To(BeEmpty())
+	})
+})
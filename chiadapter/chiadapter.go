@@ -0,0 +1,55 @@
+// Package chiadapter lets an API be activated on a github.com/go-chi/chi
+// router. Importing it registers the adapter, i.e.
+//
+//	import _ "github.com/olivoil/api.v2/chiadapter"
+//
+//	router := chi.NewRouter()
+//	api.Activate(router)
+package chiadapter
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	api "github.com/olivoil/api.v2"
+	"golang.org/x/net/context"
+)
+
+// Adapter for github.com/go-chi/chi
+type adapter struct {
+	chi.Router
+}
+
+func (router *adapter) Add(method, path string, h api.Handler) {
+	router.Method(method, Pattern(path), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.RouteContext(r.Context())
+		params := api.PathParams(path, func(name string, catchAll bool) string {
+			if rctx == nil {
+				return ""
+			}
+			if catchAll {
+				return rctx.URLParam("*")
+			}
+			return rctx.URLParam(name)
+		})
+
+		h.Serve(context.Background(), api.NewReq(w, r, params))
+	}))
+}
+
+// Pattern converts an endpoint path to a chi pattern,
+// i.e. /users/:id/*path becomes /users/{id}/*
+func Pattern(path string) string {
+	return api.RewritePath(path, func(name string) string {
+		return "{" + name + "}"
+	}, func(name string) string {
+		return "*"
+	})
+}
+
+func init() {
+	api.RegisterRouter("chi", func(v interface{}) (api.Router, bool) {
+		r, ok := v.(chi.Router)
+		return &adapter{r}, ok
+	})
+}
@@ -0,0 +1,56 @@
+package chiadapter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	api "github.com/olivoil/api.v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("chiadapter", func() {
+	newRouter := func() http.Handler {
+		a := api.New("/v1")
+		for _, path := range []string{"/users/:id", "/files/*path"} {
+			a.Add(api.Endpoint{
+				Method: "GET",
+				Path:   path,
+				Implementation: func(ctx context.Context, r *api.Req) {
+					r.Response.Header().Set("X-Id", strings.Join(r.Params.Path[":id"], "|"))
+					r.Response.Header().Set("X-Path", r.Params.Get(":path"))
+					r.NoContent(http.StatusNoContent)
+				},
+			})
+		}
+
+		router := chi.NewRouter()
+		Expect(a.Activate(router)).To(Succeed())
+		return router
+	}
+
+	It("registers the adapter", func() {
+		Expect(api.RegisteredRouters()).To(ContainElement("chi"))
+	})
+
+	It("gives the values of path parameters", func() {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/users/1,2", nil)
+		newRouter().ServeHTTP(res, req)
+		Expect(res.Code).To(Equal(http.StatusNoContent))
+		Expect(res.Header().Get("X-Id")).To(Equal("1|2"))
+
+		res = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/v1/files/docs/readme.md", nil)
+		newRouter().ServeHTTP(res, req)
+		Expect(res.Code).To(Equal(http.StatusNoContent))
+		Expect(res.Header().Get("X-Path")).To(Equal("/docs/readme.md"))
+	})
+
+	It("rewrites the parameters of endpoint paths", func() {
+		Expect(Pattern("/v1/users/:id/*path")).To(Equal("/v1/users/{id}/*"))
+	})
+})
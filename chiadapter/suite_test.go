@@ -0,0 +1,13 @@
+package chiadapter
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestChiadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "chiadapter tests")
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pborman/uuid"
+)
+
+// checkParams checks the parameters of a request against
+// Params.RequiredParams and the parameters declared by the endpoint.
+// It returns one Error per failing parameter, with the status
+// of the response: 400 for missing or malformed parameters,
+// 422 when all of them are well-formed but break a constraint.
+func (e Endpoint) checkParams(req *Req) (Errors, int) {
+	errs := Errors{}
+	status := http.StatusUnprocessableEntity
+	checked := map[string]bool{}
+
+	fail := func(p Param, err *Error) {
+		if err.HTTPStatus() == http.StatusBadRequest {
+			status = http.StatusBadRequest
+		}
+
+		err.Path = p.Key()
+		if p.In == "header" {
+			err.Source = &ErrorSource{Header: p.Name}
+		} else {
+			err.Source = &ErrorSource{Parameter: p.Name}
+		}
+		errs.Add(err)
+	}
+
+	for _, p := range e.Params {
+		checked[p.Key()] = true
+
+		values := paramValues(req, p)
+		if len(values) == 0 {
+			if p.Required || contains(req.Params.RequiredParams, p.Key()) {
+				fail(p, missingParam(p))
+			}
+			continue
+		}
+
+		for _, v := range values {
+			if err := p.check(v); err != nil {
+				fail(p, err)
+				break
+			}
+		}
+	}
+
+	for _, key := range req.Params.RequiredParams {
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+
+		p := Param{Name: key, In: "query"}
+		if strings.HasPrefix(key, ":") {
+			p = Param{Name: key[1:], In: "path"}
+		}
+
+		if len(paramValues(req, p)) == 0 {
+			fail(p, missingParam(p))
+		}
+	}
+
+	return errs, status
+}
+
+// check checks a value of the parameter against its type and constraints.
+func (p Param) check(v string) *Error {
+	t := p.GoType()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if err := checkType(t, v); err != nil {
+		return &Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Code:   "invalid_type",
+			Title:  "Invalid parameter",
+			Detail: fmt.Sprintf("parameter %q %s", p.Name, err.Error()),
+		}
+	}
+
+	invalid := func(code, detail string, args ...interface{}) *Error {
+		return &Error{
+			Status: strconv.Itoa(http.StatusUnprocessableEntity),
+			Code:   code,
+			Title:  "Invalid parameter",
+			Detail: fmt.Sprintf("parameter %q ", p.Name) + fmt.Sprintf(detail, args...),
+		}
+	}
+
+	if p.Format == "uuid" && uuid.Parse(v) == nil {
+		return invalid("uuid", "must be a uuid")
+	}
+
+	if len(p.Enum) > 0 && !contains(p.Enum, v) {
+		return invalid("enum", "must be one of %s", strings.Join(p.Enum, ", "))
+	}
+
+	if p.Pattern != "" && !compileRegexp(p.Pattern).MatchString(v) {
+		return invalid("pattern", "must match %s", p.Pattern)
+	}
+
+	if p.Min != nil || p.Max != nil {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return invalid("invalid_type", "must be a number")
+		}
+		if p.Min != nil && f < *p.Min {
+			return invalid("min", "must be at least %v", *p.Min)
+		}
+		if p.Max != nil && f > *p.Max {
+			return invalid("max", "must be at most %v", *p.Max)
+		}
+	}
+
+	if l := utf8.RuneCountInString(v); p.MinLength > 0 && l < p.MinLength {
+		return invalid("min_length", "must be at least %d characters long", p.MinLength)
+	}
+	if l := utf8.RuneCountInString(v); p.MaxLength > 0 && l > p.MaxLength {
+		return invalid("max_length", "must be at most %d characters long", p.MaxLength)
+	}
+
+	return nil
+}
+
+// checkType checks a value can be parsed into the type t.
+func checkType(t reflect.Type, v string) error {
+	var err error
+
+	if t == timeType {
+		_, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("must be a RFC 3339 date")
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		_, err = strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = strconv.ParseInt(v, 10, t.Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(v, 10, t.Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(v, t.Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+	}
+
+	return nil
+}
+
+// paramValues returns the values of a parameter in a request.
+func paramValues(req *Req, p Param) []string {
+	var values []string
+	if p.In == "header" {
+		values = req.Request.Header[http.CanonicalHeaderKey(p.Name)]
+	} else {
+		values = req.Params.GetAll(p.Key())
+	}
+
+	for _, v := range values {
+		if v != "" {
+			return values
+		}
+	}
+	return nil
+}
+
+func missingParam(p Param) *Error {
+	return &Error{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Code:   "required",
+		Title:  "Missing required parameter",
+		Detail: fmt.Sprintf("parameter %q is required", p.Name),
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+func serveParams(e Endpoint, method, url string, prepare func(r *http.Request)) (*httptest.ResponseRecorder, Errors) {
+	api := New("/v1")
+	api.Add(e)
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, nil)
+	if prepare != nil {
+		prepare(req)
+	}
+	api.ServeHTTP(res, req)
+
+	errs := Errors{}
+	if res.Code >= 400 {
+		json.Unmarshal(res.Body.Bytes(), &errs)
+	}
+	return res, errs
+}
+
+var _ = Describe("Parameter constraints", func() {
+	ok := func(ctx context.Context, r *Req) {
+		r.NoContent(http.StatusNoContent)
+	}
+
+	one, ten := 1.0, 10.0
+
+	e := Endpoint{
+		Method: "GET",
+		Path:   "/pets/:id",
+		Params: []Param{
+			{Name: "id", In: "path", Format: "uuid"},
+			{Name: "limit", Type: 0, Min: &one, Max: &ten},
+			{Name: "sort", Enum: []string{"name", "age"}},
+			{Name: "ids", Type: []int{}},
+			{Name: "q", MinLength: 2, MaxLength: 5, Pattern: "^[a-z]+$"},
+			{Name: "X-Token", In: "header", Required: true},
+		},
+		Implementation: ok,
+	}
+
+	token := func(r *http.Request) {
+		r.Header.Set("X-Token", "secret")
+	}
+
+	It("accepts valid parameters", func() {
+		res, _ := serveParams(e, "GET", "/v1/pets/4f3a4bb2-1b6f-4a0d-9a3c-3c8d2f1b6a1e?limit=5&sort=age&ids=1,2&q=abc", token)
+		Expect(res.Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("responds with a 400 for missing and malformed parameters", func() {
+		res, errs := serveParams(e, "GET", "/v1/pets/4f3a4bb2-1b6f-4a0d-9a3c-3c8d2f1b6a1e?limit=five&ids=1,b", nil)
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+		Expect(errs.Err).To(HaveLen(3))
+
+		Expect(errs.Err[0].Code).To(Equal("invalid_type"))
+		Expect(errs.Err[0].Path).To(Equal("limit"))
+		Expect(errs.Err[0].Source.Parameter).To(Equal("limit"))
+
+		Expect(errs.Err[1].Path).To(Equal("ids"))
+
+		Expect(errs.Err[2].Code).To(Equal("required"))
+		Expect(errs.Err[2].Source.Header).To(Equal("X-Token"))
+	})
+
+	It("responds with a 422 for parameters breaking constraints", func() {
+		res, errs := serveParams(e, "GET", "/v1/pets/abc?limit=11&sort=color&q=ABCDEF", token)
+		Expect(res.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(errs.Err).To(HaveLen(4))
+
+		Expect(errs.Err[0].Code).To(Equal("uuid"))
+		Expect(errs.Err[0].Path).To(Equal(":id"))
+		Expect(errs.Err[0].Source.Parameter).To(Equal("id"))
+		Expect(errs.Err[1].Code).To(Equal("max"))
+		Expect(errs.Err[2].Code).To(Equal("enum"))
+		Expect(errs.Err[3].Code).To(Equal("pattern"))
+	})
+
+	It("documents the constraints", func() {
+		api := New("/v1")
+		api.Add(e)
+
+		op := api.OpenAPI(OpenAPIInfo{Title: "pets", Version: "1"}).Paths["/v1/pets/{id}"]["get"]
+		Expect(op.Parameters[1].Schema.Type).To(Equal("integer"))
+		Expect(*op.Parameters[1].Schema.Maximum).To(Equal(10.0))
+		Expect(op.Parameters[2].Schema.Enum).To(Equal([]string{"name", "age"}))
+		Expect(op.Parameters[3].Schema.Items.Type).To(Equal("integer"))
+		Expect(op.Parameters[0].Schema.Format).To(Equal("uuid"))
+	})
+
+	It("enforces Params.RequiredParams", func() {
+		e := Endpoint{
+			Method:         "GET",
+			Path:           "/pets",
+			Implementation: ok,
+		}
+
+		res := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/pets?name=simba", nil)
+		req := WrapReq(res, r)
+		req.Params.RequiredParams = []string{"name", "age"}
+		e.Serve(context.Background(), req)
+
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+
+		errs := Errors{}
+		Expect(json.Unmarshal(res.Body.Bytes(), &errs)).To(Succeed())
+		Expect(errs.Err).To(HaveLen(1))
+		Expect(errs.Err[0].Path).To(Equal("age"))
+	})
+})
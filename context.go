@@ -0,0 +1,65 @@
+package api
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Conventions to pass values between RequestParsers, DataSources
+// and ResponseMarshallers through the context.
+
+type contextKey int
+
+const (
+	queryKey contextKey = iota
+	modelKey
+	resultKey
+)
+
+// WithQuery returns a context holding the Query of a request.
+func WithQuery(ctx context.Context, q Query) context.Context {
+	return context.WithValue(ctx, queryKey, q)
+}
+
+// QueryFrom returns the Query held by a context,
+// or a zero Query.
+func QueryFrom(ctx context.Context) Query {
+	q, _ := ctx.Value(queryKey).(Query)
+	return q
+}
+
+// WithID returns a context whose Query has the given id:
+// the id of the model to find, update or delete,
+// or of the model which has just been created.
+func WithID(ctx context.Context, id string) context.Context {
+	q := QueryFrom(ctx)
+	q.ID = id
+	return WithQuery(ctx, q)
+}
+
+// IDFrom returns the id of the Query held by a context, if any.
+func IDFrom(ctx context.Context) string {
+	return QueryFrom(ctx).ID
+}
+
+// WithModel returns a context holding the model decoded
+// from the request, to be created or updated.
+func WithModel(ctx context.Context, model interface{}) context.Context {
+	return context.WithValue(ctx, modelKey, model)
+}
+
+// ModelFrom returns the model held by a context, if any.
+func ModelFrom(ctx context.Context) interface{} {
+	return ctx.Value(modelKey)
+}
+
+// WithResult returns a context holding what a DataSource found.
+func WithResult(ctx context.Context, r Result) context.Context {
+	return context.WithValue(ctx, resultKey, r)
+}
+
+// ResultFrom returns the Result held by a context,
+// or a zero Result.
+func ResultFrom(ctx context.Context) Result {
+	r, _ := ctx.Value(resultKey).(Result)
+	return r
+}
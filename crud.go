@@ -0,0 +1,545 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Action is an action of a Resource, served by AddResource.
+type Action string
+
+const (
+	ActionIndex      Action = "index"       // GET /name
+	ActionRead       Action = "read"        // GET /name/:id
+	ActionCreate     Action = "create"      // POST /name
+	ActionUpdate     Action = "update"      // PATCH /name/:id and PUT /name/:id
+	ActionDelete     Action = "delete"      // DELETE /name/:id
+	ActionBulkCreate Action = "bulk_create" // POST /name with an array
+	ActionBulkUpdate Action = "bulk_update" // PATCH /name with an array
+	ActionBulkDelete Action = "bulk_delete" // DELETE /name?id=a,b,c, see Enable
+
+	// GET /parent/:parent_id/relationships/name, for nested resources, see Under.
+	ActionRelationship Action = "relationship"
+
+	// POST /name/:id/restore, for soft deleted models, see SoftDelete.
+	ActionRestore Action = "restore"
+)
+
+// Actions lists every Action of a Resource.
+// All are enabled by default but ActionBulkDelete, see Enable.
+var Actions = []Action{
+	ActionIndex, ActionRead, ActionCreate, ActionUpdate, ActionDelete,
+	ActionBulkCreate, ActionBulkUpdate, ActionBulkDelete, ActionRelationship, ActionRestore,
+}
+
+// ResourceOption configures the endpoints added by AddResource.
+type ResourceOption func(*resourceConfig)
+
+type resourceConfig struct {
+	model       interface{}
+	parent      *parentConfig
+	rules       *QueryRules
+	ifMatch     bool
+	softDelete  bool
+	authorize   func(context.Context, *Req) error
+	cache       map[Action]string
+	enabled     map[Action]bool
+	middleware  map[Action]MiddlewareStack
+	hooks       Hooks
+	parsers     map[Action]RequestParser
+	marshallers map[Action]ResponseMarshaller
+}
+
+// ResourceModel sets the type of the models of the resource, i.e. Pet{}.
+// Bodies of create and update requests are decoded into a new model,
+// and validated with Validate.
+// Without it, bodies are decoded into a map[string]interface{}.
+func ResourceModel(model interface{}) ResourceOption {
+	return func(c *resourceConfig) {
+		c.model = model
+	}
+}
+
+// WithQueryRules sets the allow-list of the query parameters
+// of the default RequestParsers.
+func WithQueryRules(rules QueryRules) ResourceOption {
+	return func(c *resourceConfig) {
+		c.rules = &rules
+	}
+}
+
+// RequireIfMatch makes updates and deletes, bulk or not, without an If-Match header
+// fail with 428 Precondition Required.
+func RequireIfMatch() ResourceOption {
+	return func(c *resourceConfig) {
+		c.ifMatch = true
+	}
+}
+
+// SoftDelete makes deletes mark models as deleted, with the SoftDeleter
+// of the DataSource, and adds a restore action.
+// Soft deleted models are left out of indexes and reads, unless
+// include_deleted=true is authorized by authorize, see Resource.AuthorizeDeleted.
+// AddResource panics when the DataSource is not a SoftDeleter.
+func SoftDelete(authorize func(context.Context, *Req) error) ResourceOption {
+	return func(c *resourceConfig) {
+		c.softDelete = true
+		c.authorize = authorize
+	}
+}
+
+// WithCacheControl sets the Cache-Control directives
+// of the successful responses of an action.
+func WithCacheControl(action Action, directives string) ResourceOption {
+	return func(c *resourceConfig) {
+		c.cache[action] = directives
+	}
+}
+
+// Only enables the given actions only.
+func Only(actions ...Action) ResourceOption {
+	return func(c *resourceConfig) {
+		c.enabled = map[Action]bool{}
+		for _, a := range actions {
+			c.enabled[a] = true
+		}
+	}
+}
+
+// Except disables the given actions.
+func Except(actions ...Action) ResourceOption {
+	return func(c *resourceConfig) {
+		for _, a := range actions {
+			c.enabled[a] = false
+		}
+	}
+}
+
+// Enable enables the given actions, such as ActionBulkDelete
+// which is disabled by default.
+func Enable(actions ...Action) ResourceOption {
+	return func(c *resourceConfig) {
+		for _, a := range actions {
+			c.enabled[a] = true
+		}
+	}
+}
+
+// WithMiddleware appends middleware to the stack of an action.
+func WithMiddleware(action Action, mw ...Middleware) ResourceOption {
+	return func(c *resourceConfig) {
+		c.middleware[action] = append(c.middleware[action], mw...)
+	}
+}
+
+// WithParser replaces the default RequestParser of an action.
+func WithParser(action Action, rp RequestParser) ResourceOption {
+	return func(c *resourceConfig) {
+		c.parsers[action] = rp
+	}
+}
+
+// WithMarshaller replaces the default ResponseMarshaller of an action.
+func WithMarshaller(action Action, rm ResponseMarshaller) ResourceOption {
+	return func(c *resourceConfig) {
+		c.marshallers[action] = rm
+	}
+}
+
+// AddResource adds the endpoints serving the CRUD actions of a DataSource:
+//
+//	GET    /name          index        200
+//	GET    /name/:id      read         200
+//	POST   /name          create       201 with a Location header
+//	PATCH  /name/:id      update       200
+//	PUT    /name/:id      update       200
+//	DELETE /name/:id      delete       204
+//	POST   /name          bulk_create  201, or 207 when any model failed
+//	PATCH  /name          bulk_update  200, or 207 when any model failed
+//	DELETE /name?id=a,b   bulk_delete  200, or 207 when any model failed
+//
+// Bulk creates are told apart from creates by their array body.
+// Bulk deletes are only served once enabled, see Enable.
+// Resources nested with Under are served under their parent.
+// Resources with SoftDelete get a POST /name/:id/restore action.
+// The Hooks of the API are called before the Hooks of the resource, see WithHook.
+//
+// By default, requests are parsed with a ResourceParser
+// and responses are marshalled with a ResourceMarshaller.
+func (api *API) AddResource(name string, src DataSource, opts ...ResourceOption) {
+	c := &resourceConfig{
+		enabled:     map[Action]bool{},
+		middleware:  map[Action]MiddlewareStack{},
+		parsers:     map[Action]RequestParser{},
+		marshallers: map[Action]ResponseMarshaller{},
+		cache:       map[Action]string{},
+		hooks:       Hooks{},
+	}
+	for _, a := range Actions {
+		c.enabled[a] = a != ActionBulkDelete
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if _, ok := src.(SoftDeleter); c.softDelete && !ok {
+		panic(fmt.Sprintf("api: SoftDelete expects a SoftDeleter, not %T", src))
+	}
+
+	name = strings.Trim(name, "/")
+	base := ""
+	if c.parent != nil {
+		base = "/" + c.parent.name + "/:id"
+	}
+	collection := base + "/" + name
+	member := collection + "/:id"
+	if c.parent != nil {
+		member = collection + "/:" + nestedIDParam
+	}
+
+	// serve returns the Handler of an action, running its middleware.
+	serve := func(action Action) Handler {
+		rp := c.parsers[action]
+		if rp == nil {
+			rp = ResourceParser{Action: action, Model: c.model, Rules: c.rules}
+		}
+		if c.parent != nil {
+			rp = nestedParser{RequestParser: rp, action: action, name: name, source: src, parent: c.parent, softDelete: c.softDelete, authorize: c.authorize}
+		}
+
+		rm := c.marshallers[action]
+		if rm == nil {
+			rm = ResourceMarshaller{Action: action, Name: name, Location: api.Prefix + collection}
+		}
+
+		return c.middleware[action].Then(HandlerFunc(func(ctx context.Context, r *Req) {
+			res := NewResource(r, src)
+			res.RequireIfMatch = c.ifMatch
+			res.SoftDelete = c.softDelete
+			res.AuthorizeDeleted = c.authorize
+			res.Hooks = api.Hooks.merge(c.hooks)
+			res.OnHookError = api.OnHookError
+
+			ctx, err := res.handle(ctx, action, rp)
+			if err != nil {
+				res.HandleError(err)
+				return
+			}
+
+			if err := res.Send(ctx, rm); err != nil {
+				res.HandleError(err)
+			}
+		}))
+	}
+
+	routes := []resourceRoute{
+		{ActionIndex, "GET", collection, "List " + name},
+		{ActionRead, "GET", member, "Read one of " + name},
+		{ActionCreate, "POST", collection, "Create one of " + name},
+		{ActionUpdate, "PATCH", member, "Update one of " + name},
+		{ActionUpdate, "PUT", member, "Update one of " + name},
+		{ActionDelete, "DELETE", member, "Delete one of " + name},
+		{ActionBulkUpdate, "PATCH", collection, "Update several of " + name},
+		{ActionBulkDelete, "DELETE", collection, "Delete several of " + name},
+	}
+	if c.softDelete {
+		routes = append(routes, resourceRoute{ActionRestore, "POST", member + "/restore", "Restore one of " + name})
+	}
+	if c.parent != nil {
+		routes = append(routes, resourceRoute{ActionRelationship, "GET", base + "/relationships/" + name, "List the ids of the " + name + " of one of " + c.parent.name})
+	}
+
+	for _, route := range routes {
+		action := route.action
+
+		var h Handler
+		switch {
+		case action == ActionCreate && c.enabled[ActionBulkCreate]:
+			// Bulk creates share their endpoint with creates,
+			// and are told apart by their array body.
+			single, bulk := serve(ActionCreate), serve(ActionBulkCreate)
+			h = HandlerFunc(func(ctx context.Context, r *Req) {
+				if !c.enabled[ActionCreate] || isArrayBody(r) {
+					bulk.Serve(ctx, r)
+				} else {
+					single.Serve(ctx, r)
+				}
+			})
+		case c.enabled[action]:
+			h = serve(action)
+		default:
+			continue
+		}
+
+		e := Endpoint{
+			Method:         route.method,
+			Path:           route.path,
+			Summary:        route.summary,
+			Tags:           []string{name},
+			CacheControl:   c.cache[action],
+			Implementation: h.Serve,
+		}
+
+		describeResource(&e, action, c.model)
+		api.Add(e)
+	}
+}
+
+type resourceRoute struct {
+	action  Action
+	method  string
+	path    string
+	summary string
+}
+
+// isArrayBody reports whether the body of a request is a JSON array.
+func isArrayBody(r *Req) bool {
+	body, err := r.readBody()
+	if err != nil {
+		return false
+	}
+	body = bytes.TrimLeft(body, " \t\r\n")
+	return len(body) > 0 && body[0] == '['
+}
+
+// handle dispatches an action to the matching Handle method.
+func (r *Resource) handle(ctx context.Context, action Action, rp RequestParser) (context.Context, error) {
+	switch action {
+	case ActionIndex, ActionRelationship:
+		return r.HandleIndex(ctx, rp)
+	case ActionRead:
+		return r.HandleRead(ctx, rp)
+	case ActionCreate:
+		return r.HandleCreate(ctx, rp)
+	case ActionUpdate:
+		return r.HandleUpdate(ctx, rp)
+	case ActionDelete:
+		return r.HandleDelete(ctx, rp)
+	case ActionRestore:
+		return r.HandleRestore(ctx, rp)
+	case ActionBulkCreate:
+		return r.HandleCreateMany(ctx, rp)
+	case ActionBulkUpdate:
+		return r.HandleUpdateMany(ctx, rp)
+	case ActionBulkDelete:
+		return r.HandleDeleteMany(ctx, rp)
+	}
+	return ctx, NewError(http.StatusMethodNotAllowed, "Unknown action "+string(action))
+}
+
+// describeResource documents the bodies of an action.
+func describeResource(e *Endpoint, action Action, model interface{}) {
+	if model == nil {
+		return
+	}
+
+	t := bodyType(model)
+	switch action {
+	case ActionIndex:
+		e.Responses = map[int]interface{}{http.StatusOK: reflect.MakeSlice(reflect.SliceOf(t), 0, 0).Interface()}
+	case ActionRead:
+		e.Responses = map[int]interface{}{http.StatusOK: model}
+	case ActionCreate:
+		e.Request = model
+		e.Responses = map[int]interface{}{http.StatusCreated: model}
+	case ActionUpdate:
+		e.Request = model
+		e.Responses = map[int]interface{}{http.StatusOK: model}
+	case ActionRestore:
+		e.Responses = map[int]interface{}{http.StatusOK: model}
+	case ActionDelete:
+		e.Responses = map[int]interface{}{http.StatusNoContent: nil}
+	case ActionBulkUpdate:
+		e.Request = reflect.MakeSlice(reflect.SliceOf(t), 0, 0).Interface()
+		e.Responses = map[int]interface{}{http.StatusOK: BulkResponse{}, http.StatusMultiStatus: BulkResponse{}}
+	case ActionRelationship:
+		e.Responses = map[int]interface{}{http.StatusOK: Document{}}
+	case ActionBulkDelete:
+		e.Params = []Param{{Name: "id", In: "query", Description: "Comma-separated ids of the models to delete", Required: true}}
+		e.Responses = map[int]interface{}{http.StatusOK: BulkResponse{}, http.StatusMultiStatus: BulkResponse{}}
+	}
+}
+
+// ResourceParser is the default RequestParser of AddResource.
+// It puts the Query of the request on the context with WithQuery,
+// and the decoded and validated body of create and update requests
+// with WithModel, or the body of JSON Merge Patch and JSON Patch
+// update requests with WithPatch.
+// The models of bulk creates and updates are put on the context with WithModels.
+type ResourceParser struct {
+	Action Action
+	Model  interface{} // A value of the type of the models, i.e. Pet{}
+	Rules  *QueryRules // Optional allow-list of the query parameters
+}
+
+func (p ResourceParser) ParseRequest(ctx context.Context, r *Req) (context.Context, error) {
+	// Only indexes are paginated: bulk requests must not be cut to a page of ids.
+	q, err := p.Rules.parse(r, p.Action == ActionIndex)
+	if err != nil {
+		return ctx, err
+	}
+	ctx = WithQuery(ctx, q)
+
+	switch p.Action {
+	case ActionCreate, ActionUpdate:
+	case ActionBulkCreate, ActionBulkUpdate:
+		return p.parseModels(ctx, r)
+	case ActionBulkDelete:
+		if len(q.IDs) == 0 {
+			return ctx, queryError("id", "must list the ids to delete")
+		}
+		return ctx, nil
+	default:
+		return ctx, nil
+	}
+
+	// Patches are applied to the model found by the DataSource, see Resource.Update.
+	if p.Action == ActionUpdate && isPatchMediaType(r.ContentType) {
+		body, err := r.readBody()
+		if err != nil {
+			return ctx, WrapErr(err, http.StatusBadRequest)
+		}
+		return WithPatch(ctx, Patch{MediaType: r.ContentType, Body: body}), nil
+	}
+
+	var model interface{} = &map[string]interface{}{}
+	if p.Model != nil {
+		model = reflect.New(bodyType(p.Model)).Interface()
+	}
+
+	if err := r.Decode(model); err != nil {
+		if _, ok := err.(HTTPError); ok {
+			return ctx, err
+		}
+		return ctx, WrapErr(err, http.StatusBadRequest)
+	}
+
+	if err := Validate(model); err != nil {
+		return ctx, err
+	}
+
+	return WithModel(ctx, model), nil
+}
+
+// parseModels decodes the array body of a bulk request.
+// Models are validated one by one by Resource.CreateMany and Resource.UpdateMany.
+func (p ResourceParser) parseModels(ctx context.Context, r *Req) (context.Context, error) {
+	t := reflect.TypeOf(map[string]interface{}{})
+	if p.Model != nil {
+		t = reflect.PtrTo(bodyType(p.Model))
+	}
+
+	models := reflect.New(reflect.SliceOf(t))
+	if err := r.Decode(models.Interface()); err != nil {
+		if _, ok := err.(HTTPError); ok {
+			return ctx, err
+		}
+		return ctx, WrapErr(err, http.StatusBadRequest)
+	}
+
+	s := models.Elem()
+	list := make([]interface{}, s.Len())
+	for i := range list {
+		list[i] = s.Index(i).Interface()
+	}
+	return WithModels(ctx, list), nil
+}
+
+// ResourceMarshaller is the default ResponseMarshaller of AddResource.
+// It responds with the result held by the context, see WithResult.
+// Paginated index results are sent as a Paginated body,
+// and the BulkItems of bulk actions as a BulkResponse,
+// with a 207 Multi-Status when any of them failed.
+type ResourceMarshaller struct {
+	Action Action
+	Name   string // Name of the resource, the type of the resource identifiers of relationships
+
+	// Path of the collection, for the Location header of created models.
+	// The path parameter of the parent of a nested resource is replaced by its id.
+	Location string
+}
+
+func (m ResourceMarshaller) Body(ctx context.Context) interface{} {
+	if m.Action == ActionDelete {
+		return nil
+	}
+
+	result := ResultFrom(ctx)
+	if m.isBulk() {
+		items, _ := result.Data.([]BulkItem)
+		return BulkResponse{Results: items}
+	}
+	if m.Action == ActionRelationship {
+		return relationshipDocument(result.Data, m.Name, m.location(ctx))
+	}
+	if m.Action != ActionIndex {
+		return result.Data
+	}
+
+	data := result.Data
+	if data == nil {
+		data = []interface{}{}
+	}
+
+	page := QueryFrom(ctx).Page
+	if page != (Page{}) || result.Total != nil || result.NextCursor != "" || result.PrevCursor != "" {
+		return &Paginated{
+			Data:       data,
+			Page:       page,
+			Total:      result.Total,
+			NextCursor: result.NextCursor,
+			PrevCursor: result.PrevCursor,
+		}
+	}
+	return data
+}
+
+func (m ResourceMarshaller) Headers(ctx context.Context) map[string]string {
+	if m.Action == ActionCreate {
+		if id := IDFrom(ctx); id != "" {
+			return map[string]string{"Location": m.location(ctx) + "/" + url.PathEscape(id)}
+		}
+	}
+	return nil
+}
+
+func (m ResourceMarshaller) Status(ctx context.Context) int {
+	if m.isBulk() {
+		items, _ := ResultFrom(ctx).Data.([]BulkItem)
+		for _, item := range items {
+			if item.Failed() {
+				return http.StatusMultiStatus
+			}
+		}
+		if m.Action == ActionBulkCreate {
+			return http.StatusCreated
+		}
+		return http.StatusOK
+	}
+
+	switch m.Action {
+	case ActionCreate:
+		return http.StatusCreated
+	case ActionDelete:
+		return http.StatusNoContent
+	}
+	return http.StatusOK
+}
+
+// location returns the path of the collection, see Location.
+func (m ResourceMarshaller) location(ctx context.Context) string {
+	if p, ok := ParentFrom(ctx); ok {
+		return strings.Replace(m.Location, "/:id/", "/"+url.PathEscape(p.ID)+"/", 1)
+	}
+	return m.Location
+}
+
+func (m ResourceMarshaller) isBulk() bool {
+	return m.Action == ActionBulkCreate || m.Action == ActionBulkUpdate || m.Action == ActionBulkDelete
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type crudPet struct {
+	ID   string `json:"id,omitempty" jsonapi:"primary,pets"`
+	Name string `json:"name" jsonapi:"attr,name" validate:"required"`
+}
+
+type crudPetSource struct {
+	pets map[string]*crudPet
+	next int
+}
+
+func (s *crudPetSource) FindOne(ctx context.Context) (context.Context, error) {
+	p, ok := s.pets[IDFrom(ctx)]
+	if !ok {
+		return ctx, NewError(http.StatusNotFound, "Pet not found")
+	}
+	return WithResult(ctx, Result{Data: p}), nil
+}
+
+func (s *crudPetSource) FindAll(ctx context.Context) (context.Context, error) {
+	pets := []*crudPet{}
+	for i := 1; i <= s.next; i++ {
+		if p, ok := s.pets[strconv.Itoa(i)]; ok {
+			pets = append(pets, p)
+		}
+	}
+	return WithResult(ctx, Result{Data: pets}), nil
+}
+
+func (s *crudPetSource) Create(ctx context.Context) (context.Context, error) {
+	p := ModelFrom(ctx).(*crudPet)
+	s.next++
+	p.ID = strconv.Itoa(s.next)
+	s.pets[p.ID] = p
+	return WithID(ctx, p.ID), nil
+}
+
+func (s *crudPetSource) Update(ctx context.Context) (context.Context, error) {
+	if _, ok := s.pets[IDFrom(ctx)]; !ok {
+		return ctx, NewError(http.StatusNotFound, "Pet not found")
+	}
+	p := ModelFrom(ctx).(*crudPet)
+	p.ID = IDFrom(ctx)
+	s.pets[p.ID] = p
+	return ctx, nil
+}
+
+func (s *crudPetSource) Delete(ctx context.Context) (context.Context, error) {
+	delete(s.pets, IDFrom(ctx))
+	return ctx, nil
+}
+
+var _ = Describe("AddResource", func() {
+	var (
+		api    *API
+		source *crudPetSource
+	)
+
+	BeforeEach(func() {
+		api = New("/v1")
+		source = &crudPetSource{pets: map[string]*crudPet{}}
+	})
+
+	It("serves every action", func() {
+		api.AddResource("pets", source, ResourceModel(crudPet{}))
+
+		w := serve(api, "POST", "/v1/pets", `{"name":"simba"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/v1/pets/1"))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"simba"}`))
+
+		w = serve(api, "GET", "/v1/pets/1", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"simba"}`))
+
+		w = serve(api, "PATCH", "/v1/pets/1", `{"name":"nala"}`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"nala"}`))
+
+		w = serve(api, "PUT", "/v1/pets/1", `{"name":"kiara"}`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = serve(api, "GET", "/v1/pets", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`[{"id":"1","name":"kiara"}]`))
+
+		w = serve(api, "DELETE", "/v1/pets/1", "")
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(w.Body.Len()).To(BeZero())
+
+		w = serve(api, "GET", "/v1/pets/1", "")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("escapes the ids of Location headers", func() {
+		api.AddResource("pets", NewMemorySource(crudPet{}), ResourceModel(crudPet{}))
+
+		w := serve(api, "POST", "/v1/pets", `{"id":"a b/c?","name":"simba"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/v1/pets/a%20b%2Fc%3F"))
+	})
+
+	It("validates bodies", func() {
+		api.AddResource("pets", source, ResourceModel(crudPet{}))
+
+		w := serve(api, "POST", "/v1/pets", `{}`)
+		Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+
+		w = serve(api, "POST", "/v1/pets", `{`)
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("only allows the query parameters of its rules", func() {
+		api.AddResource("pets", source, WithQueryRules(QueryRules{Sort: []string{"name"}}))
+
+		Expect(serve(api, "GET", "/v1/pets?sort=-name", "").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "GET", "/v1/pets?filter[name]=simba", "").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("enables and disables actions", func() {
+		api.AddResource("pets", source, Only(ActionIndex, ActionRead, ActionDelete), Except(ActionDelete))
+
+		Expect(serve(api, "GET", "/v1/pets", "").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "POST", "/v1/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(serve(api, "DELETE", "/v1/pets/1", "").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("runs per-action middleware", func() {
+		api.AddResource("pets", source, ResourceModel(crudPet{}), WithMiddleware(ActionCreate, MiddlewareFunc(auth)))
+
+		Expect(serve(api, "GET", "/v1/pets", "").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "POST", "/v1/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("documents the endpoints", func() {
+		api.AddResource("pets", source, ResourceModel(crudPet{}))
+
+		doc := api.OpenAPI(OpenAPIInfo{Title: "pets", Version: "1"})
+		Expect(doc.Paths).To(HaveKey("/v1/pets/{id}"))
+		Expect(doc.Paths["/v1/pets"]).To(HaveKey("post"))
+		Expect(doc.Components.Schemas).To(HaveKey("crudPet"))
+	})
+})
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v2"
+)
+
+// A Decoder implements a decoding format of request bodies.
+type Decoder interface {
+	Decode(data []byte, v interface{}) error
+}
+
+type JsonDecoder struct{}
+
+// JsonDecoder is a Decoder of JSON bodies.
+func (_ JsonDecoder) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type XmlDecoder struct{}
+
+// XmlDecoder is a Decoder of XML bodies.
+func (_ XmlDecoder) Decode(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+type MsgpackDecoder struct{}
+
+// MsgpackDecoder is a Decoder of MessagePack bodies.
+// Fields are named after their json tag, as with the JsonDecoder.
+func (_ MsgpackDecoder) Decode(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+type YamlDecoder struct{}
+
+// YamlDecoder is a Decoder of YAML bodies.
+// Fields are named after their json tag, as with the JsonDecoder.
+func (_ YamlDecoder) Decode(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(jsonCompatible(doc))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// jsonCompatible converts the maps decoded from YAML,
+// which may have any type of keys, to maps with string keys.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = jsonCompatible(val)
+		}
+	}
+	return v
+}
+
+// ProtoMessage is implemented by protocol buffer messages
+// able to unmarshal themselves, such as the ones generated by gogo/protobuf.
+type ProtoMessage interface {
+	Unmarshal(data []byte) error
+}
+
+type ProtobufDecoder struct{}
+
+// ProtobufDecoder is a Decoder of protocol buffer bodies into ProtoMessage values.
+func (_ ProtobufDecoder) Decode(data []byte, v interface{}) error {
+	m, ok := v.(ProtoMessage)
+	if !ok {
+		return fmt.Errorf("%T does not implement ProtoMessage", v)
+	}
+	return m.Unmarshal(data)
+}
+
+type FormDecoder struct{}
+
+// FormDecoder is a Decoder of urlencoded bodies into structs.
+// Fields are named after their form tag, or their json tag.
+func (_ FormDecoder) Decode(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("FormDecoder can only decode into a pointer to a struct")
+	}
+
+	errs := Errors{}
+	decodeForm(rv.Elem(), values, &errs)
+	if len(errs.Err) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func decodeForm(v reflect.Value, values url.Values, errs *Errors) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			decodeForm(fv, values, errs)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Tag.Get("form")
+		if name == "" {
+			name, _ = parseJSONTag(f.Tag.Get("json"))
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		if len(values[name]) == 0 {
+			continue
+		}
+
+		if err := setValues(fv, values[name]); err != nil {
+			errs.Add(&Error{
+				Status: strconv.Itoa(http.StatusBadRequest),
+				Code:   "invalid_type",
+				Title:  "Invalid parameter",
+				Detail: fmt.Sprintf("%q %s", name, err.Error()),
+				Path:   name,
+				Source: &ErrorSource{Parameter: name},
+			})
+		}
+	}
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{}
+)
+
+// RegisterDecoder registers the Decoder of a media type,
+// replacing the previous one if any.
+func RegisterDecoder(mediaType string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	decoders[strings.ToLower(mediaType)] = d
+}
+
+// DecoderFor returns the Decoder of a media type.
+// Structured syntax suffixes are understood, i.e. application/hal+json
+// is decoded as application/json unless it has its own Decoder.
+func DecoderFor(mediaType string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	mediaType = strings.ToLower(mediaType)
+	if d, ok := decoders[mediaType]; ok {
+		return d, true
+	}
+
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if d, ok := decoders["application/"+mediaType[i+1:]]; ok {
+			return d, true
+		}
+	}
+
+	return nil, false
+}
+
+// acceptsMediaType reports whether a media type matches
+// one of the accepted media ranges.
+func acceptsMediaType(accepted []string, mediaType string) bool {
+	for _, pattern := range accepted {
+		if matchMediaType(strings.ToLower(pattern), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupportedMediaType is the Error returned when a body cannot be decoded.
+func unsupportedMediaType(mediaType string, supported []string) *Error {
+	if supported == nil {
+		decodersMu.RLock()
+		for t := range decoders {
+			supported = append(supported, t)
+		}
+		decodersMu.RUnlock()
+	}
+
+	return &Error{
+		Status: strconv.Itoa(http.StatusUnsupportedMediaType),
+		Title:  http.StatusText(http.StatusUnsupportedMediaType),
+		Detail: "cannot read " + mediaType + ", supported media types are " + strings.Join(sortedStrings(supported), ", "),
+	}
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func init() {
+	RegisterDecoder("application/json", JsonDecoder{})
+	RegisterDecoder("application/xml", XmlDecoder{})
+	RegisterDecoder("text/xml", XmlDecoder{})
+	RegisterDecoder("application/msgpack", MsgpackDecoder{})
+	RegisterDecoder("application/x-msgpack", MsgpackDecoder{})
+	RegisterDecoder("application/yaml", YamlDecoder{})
+	RegisterDecoder("application/x-yaml", YamlDecoder{})
+	RegisterDecoder("text/yaml", YamlDecoder{})
+	RegisterDecoder("application/protobuf", ProtobufDecoder{})
+	RegisterDecoder("application/x-protobuf", ProtobufDecoder{})
+	RegisterDecoder("application/x-www-form-urlencoded", FormDecoder{})
+	RegisterDecoder(JSONAPIMediaType, JsonapiDecoder{})
+}
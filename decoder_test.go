@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type protoPet struct {
+	name string
+}
+
+func (p *protoPet) Unmarshal(data []byte) error {
+	p.name = string(data)
+	return nil
+}
+
+func decodeReq(contentType string, body []byte) *Req {
+	r, _ := http.NewRequest("POST", "/pets", bytes.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return WrapReq(httptest.NewRecorder(), r)
+}
+
+var _ = Describe("Decoders", func() {
+	It("decodes bodies according to their content type", func() {
+		packed, err := MsgpackEncoder{}.Encode(pet{ID: "1", Name: "simba"})
+		Expect(err).ToNot(HaveOccurred())
+
+		tests := []struct {
+			contentType string
+			body        []byte
+		}{
+			{"", []byte(`{"id":"1","name":"simba"}`)},
+			{"application/json; charset=utf-8", []byte(`{"id":"1","name":"simba"}`)},
+			{"application/hal+json", []byte(`{"id":"1","name":"simba"}`)},
+			{"application/xml", []byte(`<pet><ID>1</ID><Name>simba</Name></pet>`)},
+			{"application/msgpack", packed},
+			{"application/yaml", []byte("id: \"1\"\nname: simba\n")},
+			{"application/x-www-form-urlencoded", []byte(`id=1&name=simba`)},
+		}
+
+		for _, tt := range tests {
+			p := pet{}
+			Expect(decodeReq(tt.contentType, tt.body).Decode(&p)).To(Succeed(), tt.contentType)
+			Expect(p).To(Equal(pet{ID: "1", Name: "simba"}), tt.contentType)
+		}
+	})
+
+	It("decodes protocol buffers through ProtoMessage", func() {
+		p := protoPet{}
+		Expect(decodeReq("application/x-protobuf", []byte("simba")).Decode(&p)).To(Succeed())
+		Expect(p.name).To(Equal("simba"))
+
+		Expect(decodeReq("application/x-protobuf", []byte("simba")).Decode(&pet{})).ToNot(Succeed())
+	})
+
+	It("decodes forms parsed by ParseParams", func() {
+		req := decodeReq("application/x-www-form-urlencoded", []byte(`id=1&name=simba`))
+		Expect(req.ParseParams()).To(Succeed())
+
+		p := pet{}
+		Expect(req.Decode(&p)).To(Succeed())
+		Expect(p.Name).To(Equal("simba"))
+	})
+
+	It("returns a 415 error for unsupported media types", func() {
+		err := decodeReq("image/png", []byte("...")).Decode(&pet{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(err.(*Error).Detail).To(ContainSubstring("application/json"))
+	})
+
+	It("lets endpoints declare the media types they accept", func() {
+		e := Endpoint{
+			Method:   "POST",
+			Path:     "/pets",
+			Consumes: []string{"application/json", "text/*"},
+			Implementation: func(ctx context.Context, r *Req) {
+				p := pet{}
+				if err := r.Decode(&p); err != nil {
+					HandleError(r, err)
+					return
+				}
+				r.NoContent(http.StatusCreated)
+			},
+		}
+
+		for contentType, status := range map[string]int{
+			"application/json": http.StatusCreated,
+			"application/xml":  http.StatusUnsupportedMediaType,
+			"text/yaml":        http.StatusCreated,
+			"text/plain":       http.StatusUnsupportedMediaType,
+		} {
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/pets", strings.NewReader(`{"name":"simba"}`))
+			req.Header.Set("Content-Type", contentType)
+			e.ServeHTTP(res, req)
+			Expect(res.Code).To(Equal(status), contentType)
+		}
+	})
+})
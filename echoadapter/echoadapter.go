@@ -0,0 +1,50 @@
+// Package echoadapter lets an API be activated on a github.com/labstack/echo
+// router. Importing it registers the adapter, i.e.
+//
+//	import _ "github.com/olivoil/api.v2/echoadapter"
+//
+//	e := echo.New()
+//	api.Activate(e)
+package echoadapter
+
+import (
+	"github.com/labstack/echo/v4"
+	api "github.com/olivoil/api.v2"
+	"golang.org/x/net/context"
+)
+
+// Adapter for github.com/labstack/echo
+type adapter struct {
+	*echo.Echo
+}
+
+func (router *adapter) Add(method, path string, h api.Handler) {
+	router.Echo.Add(method, Pattern(path), func(c echo.Context) error {
+		params := api.PathParams(path, func(name string, catchAll bool) string {
+			if catchAll {
+				return c.Param("*")
+			}
+			return c.Param(name)
+		})
+
+		h.Serve(context.Background(), api.NewReq(c.Response(), c.Request(), params))
+		return nil
+	})
+}
+
+// Pattern converts an endpoint path to an echo pattern,
+// i.e. /users/:id/*path becomes /users/:id/*
+func Pattern(path string) string {
+	return api.RewritePath(path, func(name string) string {
+		return ":" + name
+	}, func(name string) string {
+		return "*"
+	})
+}
+
+func init() {
+	api.RegisterRouter("echo", func(v interface{}) (api.Router, bool) {
+		r, ok := v.(*echo.Echo)
+		return &adapter{r}, ok
+	})
+}
@@ -0,0 +1,13 @@
+package echoadapter
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEchoadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "echoadapter tests")
+}
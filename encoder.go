@@ -1,13 +1,22 @@
 package api
 
 // Original code borrowed from https://github.com/PuerkitoBio/martini-api-example
-// TextEncoder and XmlEncoder has been removed. If someone really needs it, let me know.
 
 // Supported tags:
 // 	 - "out" if it sets to "false", value won't be set to field
 import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"reflect"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 // An Encoder implements an encoding format of values to be sent as response to
@@ -30,8 +39,175 @@ type JsonEncoder struct{}
 
 // jsonEncoder is an Encoder that produces JSON-formatted responses.
 func (_ JsonEncoder) Encode(v ...interface{}) ([]byte, error) {
+	b, err := json.Marshal(prepare(v))
+
+	return b, err
+}
+
+type XmlEncoder struct{}
+
+// XmlEncoder is an Encoder that produces XML-formatted responses.
+func (_ XmlEncoder) Encode(v ...interface{}) ([]byte, error) {
+	b, err := xml.Marshal(prepare(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+type MsgpackEncoder struct{}
+
+// MsgpackEncoder is an Encoder that produces MessagePack responses.
+// Fields are named after their json tag, as with the JsonEncoder.
+func (_ MsgpackEncoder) Encode(v ...interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(prepare(v)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type TextEncoder struct{}
+
+// TextEncoder is an Encoder that produces plain text responses.
+// Strings, []byte, fmt.Stringer and encoding.TextMarshaler
+// are written as is, anything else is formatted with fmt.
+func (_ TextEncoder) Encode(v ...interface{}) ([]byte, error) {
+	switch data := prepare(v).(type) {
+	case string:
+		return []byte(data), nil
+	case []byte:
+		return data, nil
+	case fmt.Stringer:
+		return []byte(data.String()), nil
+	case encoding.TextMarshaler:
+		return data.MarshalText()
+	default:
+		return []byte(fmt.Sprintf("%v", data)), nil
+	}
+}
+
+type CsvEncoder struct{}
+
+// CsvEncoder is an Encoder that produces CSV responses from slices of structs,
+// with a header row of the json names of the fields.
+func (_ CsvEncoder) Encode(v ...interface{}) ([]byte, error) {
+	rows := reflect.ValueOf(prepare(v))
+	for rows.Kind() == reflect.Ptr {
+		rows = rows.Elem()
+	}
+
+	if rows.Kind() == reflect.Struct {
+		s := reflect.MakeSlice(reflect.SliceOf(rows.Type()), 1, 1)
+		s.Index(0).Set(rows)
+		rows = s
+	}
+
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return nil, errors.New("CsvEncoder can only encode slices of structs")
+	}
+
+	t := rows.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("CsvEncoder can only encode slices of structs")
+	}
+
+	columns, names := csvColumns(t)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(names)
+
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		for row.Kind() == reflect.Ptr {
+			row = row.Elem()
+		}
+
+		record := make([]string, len(columns))
+		for j, c := range columns {
+			if row.IsValid() {
+				record[j] = csvValue(row.Field(c))
+			}
+		}
+		w.Write(record)
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvColumns returns the index and name of the fields written by CsvEncoder.
+func csvColumns(t reflect.Type) ([]int, []string) {
+	columns := []int{}
+	names := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("out") == "false" {
+			continue
+		}
+
+		name, _ := parseJSONTag(f.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		columns = append(columns, i)
+		names = append(names, name)
+	}
+
+	return columns, names
+}
+
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		b, err := json.Marshal(v.Interface())
+		if err == nil {
+			return string(b)
+		}
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// prepare returns the value to encode from the arguments of Encode,
+// leaving out the fields tagged with out:"false".
+// Structs with such fields, and the slices, arrays, maps and pointers
+// holding them, are copied into values of types without these fields,
+// so that encoders drop their keys altogether.
+func prepare(v []interface{}) interface{} {
 	var data interface{} = v
-	var result interface{}
 
 	if v == nil {
 		// So that empty results produces `[]` and not `null`
@@ -40,42 +216,284 @@ func (_ JsonEncoder) Encode(v ...interface{}) ([]byte, error) {
 		data = v[0]
 	}
 
-	t := reflect.TypeOf(data)
+	if p, ok := data.(*Paginated); ok && p != nil {
+		paginated := *p
+		paginated.Data = prepare([]interface{}{p.Data})
+		return &paginated
+	}
 
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+	if data == nil {
+		return data
+	}
+
+	rv := reflect.ValueOf(data)
+	return hideFields(rv, outType(rv.Type())).Interface()
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// outStruct is a struct type without the fields tagged with out:"false"
+// of the struct type it is built from.
+type outStruct struct {
+	typ    reflect.Type
+	fields [][]int // Index of each field of typ in the original type
+}
+
+var outStructs sync.Map
+
+// outType returns the type values of t are encoded as, see prepare.
+func outType(t reflect.Type) reflect.Type {
+	return outTypeOf(t, map[reflect.Type]bool{})
+}
+
+func outTypeOf(t reflect.Type, visiting map[reflect.Type]bool) reflect.Type {
+	switch t.Kind() {
+	case reflect.Ptr:
+		if elem := outTypeOf(t.Elem(), visiting); elem != t.Elem() {
+			return reflect.PtrTo(elem)
+		}
+	case reflect.Slice:
+		if elem := outTypeOf(t.Elem(), visiting); elem != t.Elem() {
+			return reflect.SliceOf(elem)
+		}
+	case reflect.Array:
+		if elem := outTypeOf(t.Elem(), visiting); elem != t.Elem() {
+			return reflect.ArrayOf(t.Len(), elem)
+		}
+	case reflect.Map:
+		if elem := outTypeOf(t.Elem(), visiting); elem != t.Elem() {
+			return reflect.MapOf(t.Key(), elem)
+		}
+	case reflect.Struct:
+		if s := outStructOf(t, visiting); s != nil {
+			return s.typ
+		}
+		if visiting[t] {
+			// Recursive types cannot be built: the values of their
+			// recursive fields are converted at runtime instead.
+			return interfaceType
+		}
 	}
+	return t
+}
 
-	if t.Kind() == reflect.Struct {
-		result = copyStruct(reflect.ValueOf(data), t).Interface()
-	} else {
-		result = data
+// outStructOf returns the outStruct of a struct type with hidden fields,
+// or nil when it has none.
+func outStructOf(t reflect.Type, visiting map[reflect.Type]bool) *outStruct {
+	if s, ok := outStructs.Load(t); ok {
+		return s.(*outStruct)
+	}
+	if visiting[t] || !hasHiddenFields(t, map[reflect.Type]bool{}) {
+		return nil
 	}
+	visiting[t] = true
+	defer delete(visiting, t)
 
-	b, err := json.Marshal(result)
+	s := &outStruct{}
+	fields := []reflect.StructField{}
 
-	return b, err
+	// Keep the name of the type as the root element of XML documents.
+	if _, ok := t.FieldByName("XMLName"); !ok && t.Name() != "" {
+		fields = append(fields, reflect.StructField{
+			Name: "XMLName",
+			Type: reflect.TypeOf(xml.Name{}),
+			Tag:  reflect.StructTag(`xml:"` + t.Name() + `" json:"-" msgpack:"-"`),
+		})
+		s.fields = append(s.fields, nil)
+	}
+
+	for _, f := range visibleFields(t) {
+		f.Type = outTypeOf(f.Type, visiting)
+		f.Anonymous = false
+		f.Offset = 0
+		s.fields = append(s.fields, f.Index)
+		f.Index = nil
+		fields = append(fields, f)
+	}
+
+	s.typ = reflect.StructOf(fields)
+	actual, _ := outStructs.LoadOrStore(t, s)
+	return actual.(*outStruct)
 }
 
-func copyStruct(v reflect.Value, t reflect.Type) reflect.Value {
-	result := reflect.New(t).Elem()
+// visibleFields returns the exported fields of a struct type which are not
+// tagged with out:"false", with the fields of embedded structs promoted
+// as encoding/json does. Their Index is relative to t.
+func visibleFields(t reflect.Type) []reflect.StructField {
+	type field struct {
+		reflect.StructField
+		depth int
+	}
 
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+	var (
+		fields  []field
+		collect func(t reflect.Type, index []int, depth int)
+	)
+	collect = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			f.Index = append(append([]int{}, index...), i)
+
+			if f.Tag.Get("out") == "false" {
+				continue
+			}
+
+			// As with encoding/json, the exported fields of embedded structs
+			// are promoted, even when their type is unexported,
+			// unless it is a pointer.
+			if name, _ := parseJSONTag(f.Tag.Get("json")); f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr && f.PkgPath == "" {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					collect(ft, f.Index, depth+1)
+					continue
+				}
+			}
+
+			if f.PkgPath != "" {
+				continue
+			}
+
+			fields = append(fields, field{f, depth})
+		}
 	}
+	collect(t, nil, 0)
 
-	for i := 0; i < v.NumField(); i++ {
-		if tag := t.Field(i).Tag.Get("out"); tag == "false" {
-			continue
+	// The shallowest of the fields of the same name wins.
+	depths := map[string]int{}
+	for _, f := range fields {
+		if d, ok := depths[f.Name]; !ok || f.depth < d {
+			depths[f.Name] = f.depth
 		}
+	}
 
-		if v.Field(i).Kind() == reflect.Struct {
-			result.Field(i).Set(copyStruct(v.Field(i), t.Field(i).Type))
-			continue
+	visible := []reflect.StructField{}
+	for _, f := range fields {
+		if depths[f.Name] == f.depth {
+			visible = append(visible, f.StructField)
+			depths[f.Name] = -1
 		}
+	}
+	return visible
+}
+
+// hideFields converts a value into a value of type to,
+// the outType of its type, leaving out hidden fields.
+// Values of interfaces are converted into the outType of their dynamic type.
+func hideFields(v reflect.Value, to reflect.Type) reflect.Value {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Zero(to)
+		}
+		return hideFields(v.Elem(), to)
+	}
+
+	if to.Kind() == reflect.Interface {
+		out := reflect.New(to).Elem()
+		out.Set(hideFields(v, outType(v.Type())))
+		return out
+	}
+
+	if v.Type() == to && !holdsInterfaces(to) {
+		return v
+	}
 
-		result.Field(i).Set(v.Field(i))
+	switch to.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(to)
+		}
+		p := reflect.New(to.Elem())
+		p.Elem().Set(hideFields(v.Elem(), to.Elem()))
+		return p
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(to)
+		}
+		s := reflect.MakeSlice(to, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(hideFields(v.Index(i), to.Elem()))
+		}
+		return s
+
+	case reflect.Array:
+		a := reflect.New(to).Elem()
+		for i := 0; i < v.Len(); i++ {
+			a.Index(i).Set(hideFields(v.Index(i), to.Elem()))
+		}
+		return a
+
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(to)
+		}
+		m := reflect.MakeMapWithSize(to, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), hideFields(iter.Value(), to.Elem()))
+		}
+		return m
+
+	case reflect.Struct:
+		s := outStructOf(v.Type(), map[reflect.Type]bool{})
+		out := reflect.New(to).Elem()
+		for i, index := range s.fields {
+			if index == nil {
+				continue
+			}
+			// Fields of nil embedded pointers are left out.
+			f, err := v.FieldByIndexErr(index)
+			if err != nil {
+				continue
+			}
+			out.Field(i).Set(hideFields(f, to.Field(i).Type))
+		}
+		return out
+	}
+
+	return v
+}
+
+// holdsInterfaces reports whether values of t may hold interfaces
+// whose dynamic values have hidden fields.
+func holdsInterfaces(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsInterfaces(t.Elem())
+	}
+	return false
+}
+
+// hasHiddenFields reports whether a type holds structs
+// with fields tagged with out:"false".
+func hasHiddenFields(t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasHiddenFields(t.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return false
+	}
+
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("out") == "false" {
+			return true
+		}
+		if (f.PkgPath == "" || f.Anonymous) && hasHiddenFields(f.Type, seen) {
+			return true
+		}
 	}
 
-	return result
+	return false
 }
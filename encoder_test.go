@@ -2,9 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"net/http"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type Sample struct {
@@ -27,3 +30,114 @@ var _ = Describe("Encoder", func() {
 		Expect(dst.Hidden).To(Equal(""))
 	})
 })
+
+type row struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Secret  string    `json:"secret" out:"false"`
+	Created time.Time `json:"created"`
+	Tags    []string  `json:"tags"`
+}
+
+var _ = Describe("Encoders", func() {
+	created := time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)
+	rows := []row{
+		{ID: 1, Name: "simba", Secret: "s", Created: created, Tags: []string{"lion"}},
+		{ID: 2, Name: "nala, queen", Secret: "s", Created: created},
+	}
+
+	It("keeps values with unexported fields intact", func() {
+		result, err := JsonEncoder{}.Encode(created)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).To(Equal(`"2016-01-02T15:04:05Z"`))
+
+		result, err = JsonEncoder{}.Encode(&rows[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"id":1,"name":"simba","created":"2016-01-02T15:04:05Z","tags":["lion"]}`))
+	})
+
+	It("encodes XML", func() {
+		result, err := XmlEncoder{}.Encode(&Sample{Visible: "visible", Hidden: "hidden"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).To(ContainSubstring("<Visible>visible</Visible>"))
+		Expect(string(result)).ToNot(ContainSubstring("hidden"))
+	})
+
+	It("encodes MessagePack", func() {
+		result, err := MsgpackEncoder{}.Encode(&Sample{Visible: "visible", Hidden: "hidden"})
+		Expect(err).ToNot(HaveOccurred())
+
+		dst := map[string]interface{}{}
+		Expect(msgpack.Unmarshal(result, &dst)).To(Succeed())
+		Expect(dst["visible"]).To(Equal("visible"))
+		Expect(dst).ToNot(HaveKey("hidden"))
+	})
+
+	It("leaves out hidden fields of slices of structs", func() {
+		result, err := JsonEncoder{}.Encode(rows)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`[
+			{"id":1,"name":"simba","created":"2016-01-02T15:04:05Z","tags":["lion"]},
+			{"id":2,"name":"nala, queen","created":"2016-01-02T15:04:05Z","tags":null}
+		]`))
+
+		type account struct {
+			row
+			Email string `json:"email"`
+		}
+		result, err = JsonEncoder{}.Encode([]account{{row: rows[0], Email: "simba@pride.rock"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`[{"id":1,"name":"simba","created":"2016-01-02T15:04:05Z","tags":["lion"],"email":"simba@pride.rock"}]`))
+
+		result, err = XmlEncoder{}.Encode([]*Sample{{Visible: "visible", Hidden: "hidden"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).To(ContainSubstring("<Sample><Visible>visible</Visible></Sample>"))
+
+		result, err = MsgpackEncoder{}.Encode(map[string]interface{}{"rows": rows})
+		Expect(err).ToNot(HaveOccurred())
+		dst := map[string][]map[string]interface{}{}
+		Expect(msgpack.Unmarshal(result, &dst)).To(Succeed())
+		Expect(dst["rows"]).To(HaveLen(2))
+		Expect(dst["rows"][0]).ToNot(HaveKey("secret"))
+	})
+
+	It("leaves out hidden fields of paginated index responses", func() {
+		p := prepare([]interface{}{&Paginated{Data: rows}}).(*Paginated)
+		result, err := json.Marshal(p.Data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).ToNot(ContainSubstring("secret"))
+
+		api := New("")
+		api.AddResource("rows", NewMemorySource(row{}), ResourceModel(row{}), WithQueryRules(QueryRules{MaxLimit: 10}))
+		Expect(serve(api, "POST", "/rows", `{"name":"simba","secret":"s"}`).Code).To(Equal(http.StatusCreated))
+
+		for _, path := range []string{"/rows", "/rows/1"} {
+			w := serve(api, "GET", path, "", "Accept", "application/json")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"name":"simba"`))
+			Expect(w.Body.String()).ToNot(ContainSubstring("secret"))
+		}
+		Expect(serve(api, "GET", "/rows", "").Header().Get("X-Total-Count")).To(Equal("1"))
+	})
+
+	It("encodes CSV", func() {
+		result, err := CsvEncoder{}.Encode(rows)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).To(Equal("id,name,created,tags\n" +
+			"1,simba,2016-01-02T15:04:05Z,\"[\"\"lion\"\"]\"\n" +
+			"2,\"nala, queen\",2016-01-02T15:04:05Z,null\n"))
+
+		_, err = CsvEncoder{}.Encode([]string{"a"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("encodes plain text", func() {
+		result, err := TextEncoder{}.Encode("hello")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).To(Equal("hello"))
+
+		result, err = TextEncoder{}.Encode(42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(result)).To(Equal("42"))
+	})
+})
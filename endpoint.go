@@ -1,7 +1,6 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -15,9 +14,42 @@ type Endpoint struct {
 	Method string
 	Path   string
 
+	// Documentation of the endpoint
+	Summary     string
+	Description string
+	Tags        []string
+
+	// A value of the type of the request body, i.e. Pet{}
+	Request interface{}
+
+	// The media types of the request bodies the endpoint accepts,
+	// i.e. application/json or application/*.
+	// Any media type with a registered Decoder is accepted if empty.
+	Consumes []string
+
+	// A value of the type of the response body for each status code
+	Responses map[int]interface{}
+
+	// The query, path and header parameters of the endpoint.
+	// Path parameters which are not declared are added by API.Add.
+	Params []Param
+
+	// Deprecated endpoints are flagged in the generated documentation.
+	Deprecated bool
+
+	// Hidden endpoints are left out of the generated documentation.
+	Hidden bool
+
+	// Cache-Control directives of successful responses sent with Send,
+	// i.e. "private, max-age=60".
+	CacheControl string
+
 	// The middlewares to execute on the request.
 	Middleware MiddlewareStack
 
+	// The wrappers to execute around the middlewares and the implementation.
+	Wrappers WrapperStack
+
 	// Called after middleware stack was executed on the request
 	Implementation func(ctx context.Context, r *Req)
 }
@@ -27,6 +59,11 @@ func (e Endpoint) Use(mw Middleware) {
 	e.Middleware = append(e.Middleware, mw)
 }
 
+// Append a wrapper to the wrapper stack.
+func (e *Endpoint) Wrap(w Wrapper) {
+	e.Wrappers = append(e.Wrappers, w)
+}
+
 // ServeHTTP implements the http.Handler interface
 func (e Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	req := WrapReq(w, r)
@@ -48,6 +85,15 @@ func (e Endpoint) Handle(w http.ResponseWriter, r *http.Request, ps httprouter.P
 func (e Endpoint) Serve(ctx context.Context, req *Req) {
 	defer req.handlePanic()
 
+	req.cacheControl = e.CacheControl
+
+	// We must return a 415 and stop here if the endpoint does not accept the body.
+	req.consumes = e.Consumes
+	if err := e.checkContentType(req); err != nil {
+		http.Error(req.Response, err.HTTPBody(), err.HTTPStatus())
+		return
+	}
+
 	// Parse the parameters and cleanup
 	defer cleanUpParams(req)
 	err := req.ParseParams()
@@ -59,18 +105,34 @@ func (e Endpoint) Serve(ctx context.Context, req *Req) {
 		return
 	}
 
-	// call each middleware
-	for _, m := range e.Middleware {
-		c, err := m.Run(ctx, req)
-		if err != nil {
-			er := WrapErr(err, 0)
-			req.Response.WriteHeader(er.HTTPStatus())
-			fmt.Fprintln(req.Response, er.HTTPBody())
-			return
-		}
-		ctx = c
+	// We must return a 400 or a 422 if the parameters are missing or invalid.
+	if errs, status := e.checkParams(req); len(errs.Err) > 0 {
+		http.Error(req.Response, errs.HTTPBody(), status)
+		return
+	}
+
+	// Call each middleware then dispatch the request via the endpoint,
+	// all of it inside the wrappers.
+	e.Wrappers.Then(e.Middleware.Then(HandlerFunc(e.Implementation))).Serve(ctx, req)
+}
+
+// checkContentType checks the endpoint accepts the body of a request.
+func (e Endpoint) checkContentType(req *Req) *Error {
+	if len(e.Consumes) == 0 {
+		return nil
+	}
+
+	if req.Request.Body == nil || req.Request.ContentLength == 0 {
+		return nil
 	}
 
-	// Dispatch the request via the endpoint
-	e.Implementation(ctx, req)
+	mediaType := req.ResolveContentType()
+	if req.Request.Header.Get("Content-Type") == "" {
+		mediaType = "application/json"
+	}
+
+	if !acceptsMediaType(e.Consumes, mediaType) {
+		return unsupportedMediaType(mediaType, e.Consumes)
+	}
+	return nil
 }
@@ -15,6 +15,23 @@ type Error struct {
 	Title  string `json:"title,omitempty,required"`
 	Detail string `json:"detail,omitempty"`
 	Path   string `json:"path,omitempty"`
+
+	Source *ErrorSource `json:"source,omitempty"`
+}
+
+// ErrorSource references the part of the request which caused an Error
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`   // JSON pointer to a value of the request body
+	Parameter string `json:"parameter,omitempty"` // Name of a query or path parameter
+	Header    string `json:"header,omitempty"`    // Name of a header
+}
+
+// HTTPError is an error which can be written as a http response,
+// such as an Error or Errors
+type HTTPError interface {
+	error
+	HTTPStatus() int
+	HTTPBody() string
 }
 
 func NewError(status int, title string) *Error {
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// ETagger is implemented by models computing their own ETag.
+type ETagger interface {
+	ETag() string
+}
+
+// CompareAndSwapper is implemented by DataSources which atomically
+// update or delete a model only while its ETag matches an If-Match header,
+// see MatchETag, and fail with a 412 Error otherwise.
+// Other DataSources are checked by Resource before they update or delete.
+type CompareAndSwapper interface {
+	UpdateIf(ctx context.Context, ifMatch string) (context.Context, error)
+	DeleteIf(ctx context.Context, ifMatch string) (context.Context, error)
+}
+
+// ETag returns the strong ETag of a model, quoted: its own ETag
+// if it is an ETagger, or else its integer Version field,
+// or else a hash of its JSON encoding.
+func ETag(model interface{}) (string, error) {
+	if e, ok := model.(ETagger); ok {
+		return `"` + e.ETag() + `"`, nil
+	}
+
+	v := indirect(reflect.ValueOf(model))
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName("Version"); f.IsValid() {
+			switch f.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return `"` + valueString(f) + `"`, nil
+			}
+		}
+	}
+
+	b, err := json.Marshal(model)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// MatchETag reports whether an If-Match header matches an ETag,
+// with the strong comparison of RFC 7232: weak ETags never match.
+func MatchETag(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the If-Match header of the request, or a 428 Error
+// when it is missing and required.
+func (r *Resource) ifMatch() (string, error) {
+	header := r.Req.Request.Header.Get("If-Match")
+	if header == "" && r.RequireIfMatch {
+		err := NewError(http.StatusPreconditionRequired, "Precondition required")
+		err.Code = "precondition_required"
+		err.Detail = "The request must have an If-Match header"
+		err.Source = &ErrorSource{Header: "If-Match"}
+		return "", err
+	}
+	return header, nil
+}
+
+// checkIfMatch compares an If-Match header to the ETag
+// of the model found by the DataSource.
+func (r *Resource) checkIfMatch(ctx context.Context, ifMatch string) (context.Context, error) {
+	c, err := r.Source.FindOne(ctx)
+	if err != nil {
+		return c, err
+	}
+
+	etag, err := ETag(ResultFrom(c).Data)
+	if err != nil {
+		return c, err
+	}
+	if !MatchETag(ifMatch, etag) {
+		return c, preconditionFailed()
+	}
+	return c, nil
+}
+
+func preconditionFailed() *Error {
+	err := NewError(http.StatusPreconditionFailed, "Precondition failed")
+	err.Code = "precondition_failed"
+	err.Detail = "If-Match does not match the current ETag"
+	err.Source = &ErrorSource{Header: "If-Match"}
+	return err
+}
+
+// etagOf returns the ETag of the body of a response,
+// when it is a model rather than a collection of models.
+func etagOf(body interface{}) string {
+	if v := indirect(reflect.ValueOf(body)); v.Kind() != reflect.Struct {
+		return ""
+	}
+	if _, ok := body.(*Paginated); ok {
+		return ""
+	}
+
+	etag, err := ETag(body)
+	if err != nil {
+		return ""
+	}
+	return etag
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type etaggedPet struct {
+	ID string `json:"id"`
+}
+
+func (p etaggedPet) ETag() string { return "pet-" + p.ID }
+
+var _ = Describe("ETag", func() {
+	It("is computed from the model", func() {
+		Expect(ETag(etaggedPet{ID: "1"})).To(Equal(`"pet-1"`))
+		Expect(ETag(&memoryPet{Version: 3})).To(Equal(`"3"`))
+
+		etag, err := ETag(&pet{ID: "1", Name: "simba"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(etag).To(HaveLen(34))
+		Expect(ETag(pet{ID: "1", Name: "simba"})).To(Equal(etag))
+		Expect(ETag(pet{ID: "1", Name: "nala"})).ToNot(Equal(etag))
+	})
+
+	It("matches If-Match headers strongly", func() {
+		Expect(MatchETag(`"a", "b"`, `"b"`)).To(BeTrue())
+		Expect(MatchETag(`*`, `"b"`)).To(BeTrue())
+		Expect(MatchETag(`"a"`, `"b"`)).To(BeFalse())
+		Expect(MatchETag(`W/"b"`, `W/"b"`)).To(BeFalse())
+	})
+
+	for _, cas := range []bool{true, false} {
+		cas := cas
+
+		description := "with a DataSource"
+		if cas {
+			description = "with a CompareAndSwapper"
+		}
+
+		Describe(description, func() {
+			var api *API
+
+			BeforeEach(func() {
+				var source DataSource = &crudPetSource{pets: map[string]*crudPet{}}
+				if cas {
+					source = NewMemorySource(crudPet{})
+				}
+				source.Create(WithModel(context.Background(), &crudPet{Name: "simba"}))
+
+				api = New("")
+				api.AddResource("pets", source, ResourceModel(crudPet{}), Enable(ActionBulkDelete))
+			})
+
+			It("enforces If-Match", func() {
+				w := serve(api, "GET", "/pets/1", "")
+				etag := w.Header().Get("ETag")
+				Expect(etag).ToNot(BeEmpty())
+
+				w = serve(api, "PATCH", "/pets/1", `{"name":"nala"}`, "If-Match", `"stale"`)
+				Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+				Expect(w.Body.String()).To(ContainSubstring(`"header":"If-Match"`))
+
+				w = serve(api, "PATCH", "/pets/1", `{"name":"nala"}`, "If-Match", etag)
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(w.Header().Get("ETag")).ToNot(Equal(etag))
+
+				Expect(serve(api, "DELETE", "/pets/1", "", "If-Match", etag).Code).To(Equal(http.StatusPreconditionFailed))
+				Expect(serve(api, "DELETE", "/pets/1", "", "If-Match", "*").Code).To(Equal(http.StatusNoContent))
+				Expect(serve(api, "DELETE", "/pets/1", "", "If-Match", "*").Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("enforces If-Match on each model of bulk actions", func() {
+				serve(api, "POST", "/pets", `{"name":"nala"}`)
+				etag := serve(api, "GET", "/pets/1", "").Header().Get("ETag")
+
+				w := serve(api, "PATCH", "/pets", `[{"id":"1","name":"kiara"},{"id":"2","name":"kovu"}]`, "If-Match", etag)
+				Expect(w.Code).To(Equal(http.StatusMultiStatus))
+				Expect(w.Body.String()).To(ContainSubstring(`"status":412`))
+				Expect(serve(api, "GET", "/pets/1", "").Body.String()).To(ContainSubstring(`"kiara"`))
+				Expect(serve(api, "GET", "/pets/2", "").Body.String()).To(ContainSubstring(`"nala"`))
+
+				etag = serve(api, "GET", "/pets/1", "").Header().Get("ETag")
+				w = serve(api, "DELETE", "/pets?id=1,2", "", "If-Match", etag)
+				Expect(w.Code).To(Equal(http.StatusMultiStatus))
+				Expect(w.Body.String()).To(ContainSubstring(`{"id":"1","status":204}`))
+				Expect(serve(api, "GET", "/pets/2", "").Code).To(Equal(http.StatusOK))
+			})
+		})
+	}
+
+	It("can be required", func() {
+		api := New("")
+		api.AddResource("pets", NewMemorySource(crudPet{}), ResourceModel(crudPet{}), RequireIfMatch(), Enable(ActionBulkDelete))
+
+		Expect(serve(api, "POST", "/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusCreated))
+		Expect(serve(api, "PUT", "/pets/1", `{"name":"nala"}`).Code).To(Equal(http.StatusPreconditionRequired))
+		Expect(serve(api, "DELETE", "/pets/1", "").Code).To(Equal(http.StatusPreconditionRequired))
+		Expect(serve(api, "PATCH", "/pets", `[{"id":"1","name":"nala"}]`).Code).To(Equal(http.StatusPreconditionRequired))
+		Expect(serve(api, "DELETE", "/pets?id=1", "").Code).To(Equal(http.StatusPreconditionRequired))
+		Expect(serve(api, "DELETE", "/pets?id=1", "", "If-Match", "*").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "DELETE", "/pets/1", "", "If-Match", "*").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,37 @@
+module github.com/olivoil/api.v2
+
+go 1.22
+
+require (
+	github.com/antonholmquist/jason v1.0.0
+	github.com/bmizerany/pat v0.0.0-20170815010413-6226ea591a40
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/gorilla/mux v1.8.1
+	github.com/julienschmidt/httprouter v1.3.0
+	github.com/labstack/echo/v4 v4.12.0
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/onsi/ginkgo v1.16.5
+	github.com/onsi/gomega v1.33.1
+	github.com/pborman/uuid v1.2.1
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	golang.org/x/net v0.25.0
+	gopkg.in/yaml.v2 v2.3.0
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.0.0 // indirect
+	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/nxadm/tail v1.4.8 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.2 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/crypto v0.23.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"log"
+	"reflect"
+
+	"golang.org/x/net/context"
+)
+
+// HookPoint is a step of the lifecycle of a Resource where Hooks are called.
+type HookPoint string
+
+const (
+	BeforeCreate HookPoint = "before_create" // With the model to create
+	AfterCreate  HookPoint = "after_create"  // With the model created
+	BeforeUpdate HookPoint = "before_update" // With the model to update, once patched
+	AfterUpdate  HookPoint = "after_update"  // With the model updated
+	BeforeDelete HookPoint = "before_delete" // With the model to delete
+	AfterDelete  HookPoint = "after_delete"  // With the model deleted
+	AfterRead    HookPoint = "after_read"    // With the model found
+	AfterIndex   HookPoint = "after_index"   // With each of the models found
+)
+
+// Hook is called at a HookPoint of a Resource with the model of the action.
+// It can mutate the model, or veto the action by returning an *Error,
+// which is sent instead of the response.
+// AfterCreate, AfterUpdate and AfterDelete Hooks are called once the write
+// has happened: they cannot veto it, and their errors are reported
+// with the OnHookError of the Resource instead.
+// Hooks are called with pointers to the models decoded by ResourceParser
+// and found by the DataSources of this package.
+type Hook func(ctx context.Context, model interface{}) *Error
+
+// Hooks are the Hooks of a Resource, by HookPoint.
+type Hooks map[HookPoint][]Hook
+
+// Add appends hooks to a HookPoint.
+func (h Hooks) Add(point HookPoint, hooks ...Hook) {
+	h[point] = append(h[point], hooks...)
+}
+
+// merge returns the Hooks of h followed by the Hooks of other.
+func (h Hooks) merge(other Hooks) Hooks {
+	merged := Hooks{}
+	for point, hooks := range h {
+		merged.Add(point, hooks...)
+	}
+	for point, hooks := range other {
+		merged.Add(point, hooks...)
+	}
+	return merged
+}
+
+// Hook appends hooks to a HookPoint of every Resource added with AddResource.
+// They are called before the Hooks of the Resource itself.
+func (api *API) Hook(point HookPoint, hooks ...Hook) {
+	if api.Hooks == nil {
+		api.Hooks = Hooks{}
+	}
+	api.Hooks.Add(point, hooks...)
+}
+
+// WithHook appends hooks to a HookPoint of the resource.
+func WithHook(point HookPoint, hooks ...Hook) ResourceOption {
+	return func(c *resourceConfig) {
+		c.hooks.Add(point, hooks...)
+	}
+}
+
+// hook calls the Hooks of a HookPoint with a model, until one vetoes.
+func (r *Resource) hook(ctx context.Context, point HookPoint, model interface{}) error {
+	for _, h := range r.Hooks[point] {
+		if err := h(ctx, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// after calls every Hook of a HookPoint with the model written.
+// The write has already happened: their errors are reported, see OnHookError.
+func (r *Resource) after(ctx context.Context, point HookPoint, model interface{}) {
+	for _, h := range r.Hooks[point] {
+		err := h(ctx, model)
+		if err == nil {
+			continue
+		}
+
+		if r.OnHookError != nil {
+			r.OnHookError(ctx, point, err)
+		} else {
+			log.Printf("api: %s hook failed: %v", point, err)
+		}
+	}
+}
+
+// hookEach calls the Hooks of a HookPoint with each model of a slice.
+func (r *Resource) hookEach(ctx context.Context, point HookPoint, models interface{}) error {
+	if len(r.Hooks[point]) == 0 {
+		return nil
+	}
+
+	v := indirect(reflect.ValueOf(models))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return r.hook(ctx, point, models)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		m := v.Index(i)
+		if m.CanAddr() && m.Kind() != reflect.Ptr && m.Kind() != reflect.Map && m.Kind() != reflect.Interface {
+			m = m.Addr()
+		}
+		if err := r.hook(ctx, point, m.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Hooks", func() {
+	var (
+		api   *API
+		calls []string
+	)
+
+	record := func(point HookPoint) Hook {
+		return func(ctx context.Context, model interface{}) *Error {
+			calls = append(calls, string(point)+" "+model.(*crudPet).Name)
+			return nil
+		}
+	}
+
+	BeforeEach(func() {
+		calls = nil
+		api = New("")
+
+		var opts []ResourceOption
+		for _, point := range []HookPoint{BeforeCreate, AfterCreate, BeforeUpdate, AfterUpdate, BeforeDelete, AfterDelete, AfterRead, AfterIndex} {
+			opts = append(opts, WithHook(point, record(point)))
+		}
+		opts = append(opts, ResourceModel(crudPet{}), Enable(ActionBulkDelete))
+
+		api.AddResource("pets", NewMemorySource(crudPet{}), opts...)
+	})
+
+	It("are called around every action", func() {
+		serve(api, "POST", "/pets", `{"name":"simba"}`)
+		serve(api, "GET", "/pets/1", "")
+		serve(api, "PATCH", "/pets/1", `{"name":"nala"}`)
+		serve(api, "GET", "/pets", "")
+		serve(api, "DELETE", "/pets/1", "")
+
+		Expect(calls).To(Equal([]string{
+			"before_create simba",
+			"after_create simba",
+			"after_read simba",
+			"before_update nala",
+			"after_update nala",
+			"after_index nala",
+			"before_delete nala",
+			"after_delete nala",
+		}))
+	})
+
+	It("are called for each model of bulk actions", func() {
+		serve(api, "POST", "/pets", `[{"name":"simba"},{},{"name":"nala"}]`)
+		serve(api, "DELETE", "/pets?id=1,2", "")
+
+		Expect(calls).To(Equal([]string{
+			"before_create simba",
+			"before_create nala",
+			"after_create simba",
+			"after_create nala",
+			"before_delete simba",
+			"after_delete simba",
+			"before_delete nala",
+			"after_delete nala",
+		}))
+	})
+
+	It("can mutate models", func() {
+		api.Hook(BeforeCreate, func(ctx context.Context, model interface{}) *Error {
+			p := model.(*crudPet)
+			p.Name = strings.ToLower(p.Name)
+			return nil
+		})
+
+		w := serve(api, "POST", "/pets", `{"name":"SIMBA"}`)
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"simba"}`))
+		Expect(calls[0]).To(Equal("before_create simba"))
+	})
+
+	It("can veto actions", func() {
+		veto := func(ctx context.Context, model interface{}) *Error {
+			if model.(*crudPet).Name == "scar" {
+				return NewError(http.StatusForbidden, "No lions named scar")
+			}
+			return nil
+		}
+		api.Hook(BeforeCreate, veto)
+		api.Hook(BeforeUpdate, veto)
+
+		w := serve(api, "POST", "/pets", `{"name":"scar"}`)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Body.String()).To(ContainSubstring("No lions named scar"))
+		Expect(calls).To(BeEmpty())
+
+		Expect(serve(api, "POST", "/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusCreated))
+		Expect(serve(api, "PUT", "/pets/1", `{"name":"scar"}`).Code).To(Equal(http.StatusForbidden))
+		Expect(serve(api, "GET", "/pets/1", "").Body.String()).To(ContainSubstring(`"simba"`))
+
+		w = serve(api, "POST", "/pets", `[{"name":"scar"},{"name":"nala"}]`)
+		Expect(w.Code).To(Equal(http.StatusMultiStatus))
+		Expect(w.Body.String()).To(ContainSubstring(`"status":403`))
+	})
+
+	It("reports the errors of hooks after writes which have happened", func() {
+		var failed []HookPoint
+		api.OnHookError = func(ctx context.Context, point HookPoint, err *Error) {
+			Expect(err.Title).To(Equal("Failed"))
+			failed = append(failed, point)
+		}
+
+		fail := func(ctx context.Context, model interface{}) *Error {
+			return NewError(http.StatusInternalServerError, "Failed")
+		}
+		api.Hook(AfterCreate, fail)
+		api.Hook(AfterUpdate, fail)
+		api.Hook(AfterDelete, fail)
+
+		Expect(serve(api, "POST", "/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusCreated))
+		Expect(serve(api, "PATCH", "/pets/1", `{"name":"nala"}`).Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "POST", "/pets", `[{"name":"kiara"}]`).Code).To(Equal(http.StatusCreated))
+		Expect(serve(api, "DELETE", "/pets/1", "").Code).To(Equal(http.StatusNoContent))
+
+		Expect(calls).To(ContainElement("after_create simba"))
+		Expect(calls).To(ContainElement("after_update nala"))
+		Expect(calls).To(ContainElement("after_create kiara"))
+		Expect(calls).To(ContainElement("after_delete nala"))
+		Expect(failed).To(Equal([]HookPoint{AfterCreate, AfterUpdate, AfterCreate, AfterDelete}))
+	})
+})
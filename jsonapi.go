@@ -0,0 +1,738 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSONAPIMediaType is the media type of jsonapi.org documents.
+const JSONAPIMediaType = "application/vnd.api+json"
+
+// Supported tags of models marshalled as jsonapi.org resources:
+// 	 - `jsonapi:"primary,type"` the id of the resource and its type
+// 	 - `jsonapi:"attr,name"` an attribute, omitted when empty with `jsonapi:"attr,name,omitempty"`
+// 	 - `jsonapi:"relation,name"` a relationship to a model, or a slice of models
+//
+// Fields tagged with out:"false" are never marshalled.
+
+// Document is a jsonapi.org document.
+type Document struct {
+	Data     interface{}            `json:"data"` // *ResourceObject, []*ResourceObject or nil
+	Included []*ResourceObject      `json:"included,omitempty"`
+	Links    map[string]string      `json:"links,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+}
+
+// ResourceObject is a resource of a jsonapi.org document.
+type ResourceObject struct {
+	Type          string                   `json:"type"`
+	ID            string                   `json:"id,omitempty"`
+	Attributes    map[string]interface{}   `json:"attributes,omitempty"`
+	Relationships map[string]*Relationship `json:"relationships,omitempty"`
+	Links         map[string]string        `json:"links,omitempty"`
+	Meta          map[string]interface{}   `json:"meta,omitempty"`
+}
+
+// Relationship is a relationship of a ResourceObject.
+type Relationship struct {
+	Data  interface{}       `json:"data"` // *ResourceIdentifier, []*ResourceIdentifier or nil
+	Links map[string]string `json:"links,omitempty"`
+}
+
+// ResourceIdentifier identifies a resource in a Relationship.
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// JSONAPILinker is implemented by models with links.
+type JSONAPILinker interface {
+	JSONAPILinks() map[string]string
+}
+
+// JSONAPIMetaer is implemented by models with meta information.
+type JSONAPIMetaer interface {
+	JSONAPIMeta() map[string]interface{}
+}
+
+// DocumentOptions controls how models are marshalled into a Document.
+type DocumentOptions struct {
+	Include []string            // Relationship paths to include, i.e. owner.pets
+	Fields  map[string][]string // Sparse fieldsets, by resource type
+	Links   map[string]string
+	Meta    map[string]interface{}
+}
+
+// ParseDocumentOptions reads the include and fields[type] parameters of a query.
+func ParseDocumentOptions(query Values) DocumentOptions {
+	opts := DocumentOptions{Fields: map[string][]string{}}
+
+	for _, include := range splitValues(query["include"], ",") {
+		if include != "" {
+			opts.Include = append(opts.Include, include)
+		}
+	}
+
+	for key, values := range query {
+		if strings.HasPrefix(key, "fields[") && strings.HasSuffix(key, "]") {
+			opts.Fields[key[len("fields["):len(key)-1]] = splitValues(values, ",")
+		}
+	}
+
+	return opts
+}
+
+// MarshalDocument marshals a model, or a slice of models, into a Document.
+func MarshalDocument(v interface{}, opts DocumentOptions) (*Document, error) {
+	b := &documentBuilder{opts: opts, seen: map[string]bool{}}
+	include := parseIncludes(opts.Include)
+	doc := &Document{Links: opts.Links, Meta: opts.Meta}
+
+	if t := reflect.TypeOf(v); t != nil {
+		if err := checkIncludes(t, include, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return doc, nil
+		}
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return doc, nil
+	}
+
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		data := []*ResourceObject{}
+		for i := 0; i < rv.Len(); i++ {
+			ro, err := b.resource(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if ro != nil {
+				data = append(data, ro)
+			}
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+			if err := b.includeRelations(rv.Index(i), include); err != nil {
+				return nil, err
+			}
+		}
+
+		doc.Data = data
+	} else {
+		ro, err := b.resource(rv)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.includeRelations(rv, include); err != nil {
+			return nil, err
+		}
+		doc.Data = ro
+	}
+
+	doc.Included = b.included
+	return doc, nil
+}
+
+// NewDocument marshals a model, or a slice of models, into a Document
+// honoring the include and fields[type] parameters of a request.
+func NewDocument(req *Req, v interface{}) (*Document, error) {
+	return MarshalDocument(v, ParseDocumentOptions(req.Params.Query))
+}
+
+// includeTree holds relationship paths to include, i.e. owner.pets
+type includeTree map[string]includeTree
+
+func parseIncludes(paths []string) includeTree {
+	tree := includeTree{}
+	for _, path := range paths {
+		node := tree
+		for _, name := range strings.Split(path, ".") {
+			if node[name] == nil {
+				node[name] = includeTree{}
+			}
+			node = node[name]
+		}
+	}
+	return tree
+}
+
+// checkIncludes returns a 400 Error for the first path of the include tree
+// which is not a relationship of the models of type t.
+func checkIncludes(t reflect.Type, include includeTree, prefix string) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || len(include) == 0 {
+		return nil
+	}
+
+	m, err := jsonapiModelOf(t)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(include))
+	for name := range include {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, ok := m.relation(name)
+		if !ok {
+			return queryError("include", fmt.Sprintf("%q is not a relationship", prefix+name))
+		}
+		if err := checkIncludes(t.FieldByIndex(f.index).Type, include[name], prefix+name+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type documentBuilder struct {
+	opts     DocumentOptions
+	included []*ResourceObject
+	seen     map[string]bool
+}
+
+// resource marshals a model into a ResourceObject.
+func (b *documentBuilder) resource(v reflect.Value) (*ResourceObject, error) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	m, err := jsonapiModelOf(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	ro := &ResourceObject{Type: m.typ, ID: m.id(v)}
+	b.seen[ro.Type+":"+ro.ID] = true
+
+	fields, sparse := b.opts.Fields[m.typ]
+
+	for _, f := range m.attrs {
+		if sparse && !contains(fields, f.name) {
+			continue
+		}
+
+		fv := v.FieldByIndex(f.index)
+		if f.omitempty && isZero(fv) {
+			continue
+		}
+
+		if ro.Attributes == nil {
+			ro.Attributes = map[string]interface{}{}
+		}
+		ro.Attributes[f.name] = prepare([]interface{}{fv.Interface()})
+	}
+
+	for _, f := range m.relations {
+		if sparse && !contains(fields, f.name) {
+			continue
+		}
+
+		rel, err := relationship(v.FieldByIndex(f.index))
+		if err != nil {
+			return nil, err
+		}
+
+		if ro.Relationships == nil {
+			ro.Relationships = map[string]*Relationship{}
+		}
+		ro.Relationships[f.name] = rel
+	}
+
+	if l, ok := v.Interface().(JSONAPILinker); ok {
+		ro.Links = l.JSONAPILinks()
+	} else if v.CanAddr() {
+		if l, ok := v.Addr().Interface().(JSONAPILinker); ok {
+			ro.Links = l.JSONAPILinks()
+		}
+	}
+
+	if m, ok := v.Interface().(JSONAPIMetaer); ok {
+		ro.Meta = m.JSONAPIMeta()
+	} else if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(JSONAPIMetaer); ok {
+			ro.Meta = m.JSONAPIMeta()
+		}
+	}
+
+	return ro, nil
+}
+
+// relationship marshals the related models of a relation field.
+func relationship(v reflect.Value) (*Relationship, error) {
+	identify := func(v reflect.Value) (*ResourceIdentifier, error) {
+		v = indirect(v)
+		if !v.IsValid() {
+			return nil, nil
+		}
+		m, err := jsonapiModelOf(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		return &ResourceIdentifier{Type: m.typ, ID: m.id(v)}, nil
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		data := []*ResourceIdentifier{}
+		for i := 0; i < v.Len(); i++ {
+			ri, err := identify(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if ri != nil {
+				data = append(data, ri)
+			}
+		}
+		return &Relationship{Data: data}, nil
+	}
+
+	ri, err := identify(v)
+	if err != nil {
+		return nil, err
+	}
+	if ri == nil {
+		return &Relationship{}, nil
+	}
+	return &Relationship{Data: ri}, nil
+}
+
+// includeRelations adds the related models of a model
+// to the included resources, following the include tree.
+func (b *documentBuilder) includeRelations(v reflect.Value, include includeTree) error {
+	v = indirect(v)
+	if !v.IsValid() || len(include) == 0 {
+		return nil
+	}
+
+	m, err := jsonapiModelOf(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range m.relations {
+		sub, ok := include[f.name]
+		if !ok {
+			continue
+		}
+
+		related := []reflect.Value{}
+		fv := v.FieldByIndex(f.index)
+		if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+			for i := 0; i < fv.Len(); i++ {
+				related = append(related, fv.Index(i))
+			}
+		} else {
+			related = append(related, fv)
+		}
+
+		for _, r := range related {
+			r = indirect(r)
+			if !r.IsValid() {
+				continue
+			}
+
+			rm, err := jsonapiModelOf(r.Type())
+			if err != nil {
+				return err
+			}
+
+			if !b.seen[rm.typ+":"+rm.id(r)] {
+				ro, err := b.resource(r)
+				if err != nil {
+					return err
+				}
+				b.included = append(b.included, ro)
+			}
+
+			if err := b.includeRelations(r, sub); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalDocument unmarshals a Document into a model,
+// or into a slice of models.
+// Related resources are set with their id only.
+func UnmarshalDocument(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("api: UnmarshalDocument expects a pointer")
+	}
+
+	doc := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return &Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Code:   "invalid_body",
+			Title:  "Invalid body",
+			Detail: err.Error(),
+		}
+	}
+
+	target := rv.Elem()
+	errs := Errors{}
+
+	if target.Kind() == reflect.Slice {
+		raws := []rawResource{}
+		if err := json.Unmarshal(doc.Data, &raws); err != nil {
+			return invalidDocument("/data", "data must be an array of resources")
+		}
+
+		s := reflect.MakeSlice(target.Type(), len(raws), len(raws))
+		for i, raw := range raws {
+			elem := s.Index(i)
+			if elem.Kind() == reflect.Ptr {
+				elem.Set(reflect.New(elem.Type().Elem()))
+				elem = elem.Elem()
+			}
+			if err := setResource(elem, raw, "/data/"+strconv.Itoa(i), &errs); err != nil {
+				return err
+			}
+		}
+		target.Set(s)
+	} else {
+		raw := rawResource{}
+		if err := json.Unmarshal(doc.Data, &raw); err != nil || len(doc.Data) == 0 {
+			return invalidDocument("/data", "data must be a resource")
+		}
+
+		for target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		if err := setResource(target, raw, "/data", &errs); err != nil {
+			return err
+		}
+	}
+
+	if len(errs.Err) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type rawResource struct {
+	Type          string                     `json:"type"`
+	ID            string                     `json:"id"`
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"relationships"`
+}
+
+// setResource sets a model from a resource of a document.
+// Invalid values are added to errs, while the returned error
+// means the document cannot be processed at all.
+func setResource(v reflect.Value, raw rawResource, pointer string, errs *Errors) error {
+	m, err := jsonapiModelOf(v.Type())
+	if err != nil {
+		return err
+	}
+
+	if raw.Type != m.typ {
+		return &Error{
+			Status: strconv.Itoa(http.StatusConflict),
+			Code:   "type",
+			Title:  "Invalid resource type",
+			Detail: fmt.Sprintf("expected %q resources, got %q", m.typ, raw.Type),
+			Path:   pointer + "/type",
+			Source: &ErrorSource{Pointer: pointer + "/type"},
+		}
+	}
+
+	if raw.ID != "" {
+		if err := setValue(v.FieldByIndex(m.primary), raw.ID); err != nil {
+			errs.Add(invalidDocument(pointer+"/id", "id "+err.Error()))
+		}
+	}
+
+	for _, f := range m.attrs {
+		value, ok := raw.Attributes[f.name]
+		if !ok {
+			continue
+		}
+
+		if err := json.Unmarshal(value, v.FieldByIndex(f.index).Addr().Interface()); err != nil {
+			errs.Add(invalidDocument(pointer+"/attributes/"+escapePointer(f.name), err.Error()))
+		}
+	}
+
+	for _, f := range m.relations {
+		rel, ok := raw.Relationships[f.name]
+		if !ok {
+			continue
+		}
+
+		p := pointer + "/relationships/" + escapePointer(f.name) + "/data"
+		if err := setRelation(v.FieldByIndex(f.index), rel.Data, p); err != nil {
+			errs.Add(err)
+		}
+	}
+
+	return nil
+}
+
+// setRelation sets a relation field from the data of a relationship.
+func setRelation(v reflect.Value, data json.RawMessage, pointer string) *Error {
+	if len(data) == 0 || string(data) == "null" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	setIdentifier := func(v reflect.Value, ri ResourceIdentifier, pointer string) *Error {
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.New(v.Type().Elem()))
+			v = v.Elem()
+		}
+
+		m, err := jsonapiModelOf(v.Type())
+		if err != nil {
+			return invalidDocument(pointer, fmt.Sprintf("data cannot be set to a %s", v.Type()))
+		}
+		if ri.Type != m.typ {
+			return invalidDocument(pointer+"/type", fmt.Sprintf("expected %q resources, got %q", m.typ, ri.Type))
+		}
+		if err := setValue(v.FieldByIndex(m.primary), ri.ID); err != nil {
+			return invalidDocument(pointer+"/id", "id "+err.Error())
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		ris := []ResourceIdentifier{}
+		if err := json.Unmarshal(data, &ris); err != nil {
+			return invalidDocument(pointer, "data must be an array of resource identifiers")
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(ris), len(ris))
+		for i, ri := range ris {
+			if err := setIdentifier(s.Index(i), ri, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	ri := ResourceIdentifier{}
+	if err := json.Unmarshal(data, &ri); err != nil {
+		return invalidDocument(pointer, "data must be a resource identifier")
+	}
+	return setIdentifier(v, ri, pointer)
+}
+
+func invalidDocument(pointer, detail string) *Error {
+	return &Error{
+		Status: strconv.Itoa(http.StatusUnprocessableEntity),
+		Title:  "Invalid document",
+		Detail: detail,
+		Path:   pointer,
+		Source: &ErrorSource{Pointer: pointer},
+	}
+}
+
+// jsonapiModel describes how a struct is marshalled as a resource.
+type jsonapiModel struct {
+	typ       string
+	primary   []int
+	attrs     []jsonapiField
+	relations []jsonapiField
+}
+
+type jsonapiField struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+func (m *jsonapiModel) id(v reflect.Value) string {
+	id := v.FieldByIndex(m.primary)
+	if isZero(id) {
+		return ""
+	}
+	return valueString(id)
+}
+
+// relation returns the relation field of a model by name.
+func (m *jsonapiModel) relation(name string) (jsonapiField, bool) {
+	for _, f := range m.relations {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return jsonapiField{}, false
+}
+
+var jsonapiModels sync.Map
+
+// jsonapiModelOf returns the description of a model type from its tags.
+func jsonapiModelOf(t reflect.Type) (*jsonapiModel, error) {
+	if m, ok := jsonapiModels.Load(t); ok {
+		return m.(*jsonapiModel), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("api: %s is not a jsonapi model", t)
+	}
+
+	m := &jsonapiModel{}
+	addJsonapiFields(m, t, nil)
+
+	if m.primary == nil {
+		return nil, fmt.Errorf("api: %s has no field tagged with jsonapi:\"primary,type\"", t)
+	}
+
+	jsonapiModels.Store(t, m)
+	return m, nil
+}
+
+func addJsonapiFields(m *jsonapiModel, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+
+		tag := f.Tag.Get("jsonapi")
+		if tag == "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				addJsonapiFields(m, f.Type, idx)
+			}
+			continue
+		}
+
+		if f.PkgPath != "" || f.Tag.Get("out") == "false" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "primary":
+			m.typ = parts[1]
+			m.primary = idx
+		case "attr":
+			m.attrs = append(m.attrs, jsonapiField{
+				name:      parts[1],
+				index:     idx,
+				omitempty: len(parts) > 2 && parts[2] == "omitempty",
+			})
+		case "relation":
+			m.relations = append(m.relations, jsonapiField{name: parts[1], index: idx})
+		}
+	}
+}
+
+type JsonapiEncoder struct{}
+
+// JsonapiEncoder is an Encoder that produces jsonapi.org documents.
+// Models are marshalled with MarshalDocument, and documents as is.
+func (_ JsonapiEncoder) Encode(v ...interface{}) ([]byte, error) {
+	return JsonapiEncoder{}.encode(v, DocumentOptions{})
+}
+
+// EncodeRequest honors the include and fields[type] parameters of the request.
+// Paginated models get pagination links.
+func (_ JsonapiEncoder) EncodeRequest(req *Req, v interface{}) ([]byte, error) {
+	opts := ParseDocumentOptions(req.Params.Query)
+	if p, ok := v.(*Paginated); ok {
+		opts.Links = p.Links(req.Request.URL)
+	}
+	return JsonapiEncoder{}.encode([]interface{}{v}, opts)
+}
+
+func (_ JsonapiEncoder) encode(v []interface{}, opts DocumentOptions) ([]byte, error) {
+	var data interface{} = v
+	if len(v) == 1 {
+		data = v[0]
+	}
+
+	if p, ok := data.(*Paginated); ok {
+		data = p.Data
+		if p.Total != nil {
+			if opts.Meta == nil {
+				opts.Meta = map[string]interface{}{}
+			}
+			opts.Meta["total"] = *p.Total
+		}
+	}
+
+	doc, ok := data.(*Document)
+	if !ok {
+		var err error
+		doc, err = MarshalDocument(data, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+// CanEncode reports whether v is a Document, or models tagged
+// with a jsonapi primary field, or a slice or Paginated of them.
+func (_ JsonapiEncoder) CanEncode(v interface{}) bool {
+	if p, ok := v.(*Paginated); ok {
+		v = p.Data
+	}
+	if _, ok := v.(*Document); ok {
+		return true
+	}
+
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		_, err := jsonapiModelOf(t)
+		return err == nil
+	}
+	return false
+}
+
+type JsonapiDecoder struct{}
+
+// JsonapiDecoder is a Decoder of jsonapi.org documents into models.
+func (_ JsonapiDecoder) Decode(data []byte, v interface{}) error {
+	return UnmarshalDocument(data, v)
+}
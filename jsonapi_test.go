@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type jsonapiOwner struct {
+	ID   int            `jsonapi:"primary,owners"`
+	Name string         `jsonapi:"attr,name"`
+	Pets []*jsonapiPet  `jsonapi:"relation,pets"`
+	Best *jsonapiFriend `jsonapi:"relation,best-friend"`
+}
+
+type jsonapiFriend struct {
+	ID   string `jsonapi:"primary,friends"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type jsonapiPet struct {
+	ID     string        `jsonapi:"primary,pets"`
+	Name   string        `jsonapi:"attr,name"`
+	Age    int           `jsonapi:"attr,age,omitempty"`
+	Secret string        `jsonapi:"attr,secret" out:"false"`
+	Owner  *jsonapiOwner `jsonapi:"relation,owner"`
+}
+
+type jsonapiTag struct {
+	ID    string `jsonapi:"primary,tags"`
+	Owner string `jsonapi:"relation,owner"`
+}
+
+func (p jsonapiPet) JSONAPILinks() map[string]string {
+	return map[string]string{"self": "/pets/" + p.ID}
+}
+
+type petKey int
+
+var petCtxKey petKey
+
+type jsonapiPetParser struct{}
+
+func (jsonapiPetParser) ParseRequest(ctx context.Context, r *Req) (context.Context, error) {
+	p := &jsonapiPet{}
+	if err := r.Decode(p); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, petCtxKey, p), nil
+}
+
+type jsonapiPetSource struct {
+	pets map[string]*jsonapiPet
+}
+
+func (s *jsonapiPetSource) FindOne(ctx context.Context) (context.Context, error) {
+	p := ctx.Value(petCtxKey).(*jsonapiPet)
+	return context.WithValue(ctx, petCtxKey, s.pets[p.ID]), nil
+}
+
+func (s *jsonapiPetSource) FindAll(ctx context.Context) (context.Context, error) { return ctx, nil }
+func (s *jsonapiPetSource) Update(ctx context.Context) (context.Context, error)  { return ctx, nil }
+func (s *jsonapiPetSource) Delete(ctx context.Context) (context.Context, error)  { return ctx, nil }
+
+func (s *jsonapiPetSource) Create(ctx context.Context) (context.Context, error) {
+	p := ctx.Value(petCtxKey).(*jsonapiPet)
+	p.ID = "2"
+	p.Owner = &jsonapiOwner{ID: p.Owner.ID, Name: "mufasa"}
+	s.pets[p.ID] = p
+	return ctx, nil
+}
+
+var _ = Describe("JSON:API", func() {
+	simba := &jsonapiPet{ID: "1", Name: "simba", Secret: "s"}
+	nala := &jsonapiPet{ID: "2", Name: "nala", Age: 3}
+	owner := &jsonapiOwner{ID: 7, Name: "mufasa", Pets: []*jsonapiPet{simba, nala}, Best: &jsonapiFriend{ID: "z", Name: "zazu"}}
+	simba.Owner = owner
+	nala.Owner = owner
+
+	It("marshals a resource", func() {
+		doc, err := MarshalDocument(simba, DocumentOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		b, _ := json.Marshal(doc)
+		Expect(b).To(MatchJSON(`{"data":{
+			"type":"pets","id":"1",
+			"attributes":{"name":"simba"},
+			"relationships":{"owner":{"data":{"type":"owners","id":"7"}}},
+			"links":{"self":"/pets/1"}
+		}}`))
+	})
+
+	It("marshals compound documents with sparse fieldsets", func() {
+		opts := ParseDocumentOptions(Values{
+			"include":        {"owner.pets,owner.best-friend"},
+			"fields[owners]": {"name,best-friend"},
+			"fields[pets]":   {"name"},
+		})
+		Expect(opts.Include).To(Equal([]string{"owner.pets", "owner.best-friend"}))
+
+		opts.Meta = map[string]interface{}{"total": 2}
+		doc, err := MarshalDocument([]*jsonapiPet{simba}, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		b, _ := json.Marshal(doc)
+		Expect(b).To(MatchJSON(`{
+			"data":[{"type":"pets","id":"1","attributes":{"name":"simba"},"links":{"self":"/pets/1"}}],
+			"included":[
+				{"type":"owners","id":"7","attributes":{"name":"mufasa"},"relationships":{"best-friend":{"data":{"type":"friends","id":"z"}}}},
+				{"type":"pets","id":"2","attributes":{"name":"nala"},"links":{"self":"/pets/2"}},
+				{"type":"friends","id":"z","attributes":{"name":"zazu"}}
+			],
+			"meta":{"total":2}
+		}`))
+	})
+
+	It("unmarshals a resource", func() {
+		p := jsonapiPet{}
+		err := UnmarshalDocument([]byte(`{"data":{
+			"type":"pets","id":"3",
+			"attributes":{"name":"kiara","age":1},
+			"relationships":{"owner":{"data":{"type":"owners","id":"9"}}}
+		}}`), &p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.ID).To(Equal("3"))
+		Expect(p.Name).To(Equal("kiara"))
+		Expect(p.Age).To(Equal(1))
+		Expect(p.Owner.ID).To(Equal(9))
+
+		o := jsonapiOwner{}
+		err = UnmarshalDocument([]byte(`{"data":{
+			"type":"owners",
+			"relationships":{"pets":{"data":[{"type":"pets","id":"1"},{"type":"pets","id":"2"}]},"best-friend":{"data":null}}
+		}}`), &o)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.Pets).To(HaveLen(2))
+		Expect(o.Pets[1].ID).To(Equal("2"))
+		Expect(o.Best).To(BeNil())
+
+		pets := []jsonapiPet{}
+		err = UnmarshalDocument([]byte(`{"data":[{"type":"pets","id":"1"},{"type":"pets","id":"2"}]}`), &pets)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pets).To(HaveLen(2))
+	})
+
+	It("reports invalid documents", func() {
+		err := UnmarshalDocument([]byte(`{"data":{"type":"owners","id":"1"}}`), &jsonapiPet{})
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusConflict))
+
+		err = UnmarshalDocument([]byte(`{"data":{"type":"pets","attributes":{"age":"old"}}}`), &jsonapiPet{})
+		errs := err.(Errors)
+		Expect(errs.HTTPStatus()).To(Equal(http.StatusUnprocessableEntity))
+		Expect(errs.Err[0].Source.Pointer).To(Equal("/data/attributes/age"))
+
+		err = UnmarshalDocument([]byte(`{"data":`), &jsonapiPet{})
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusBadRequest))
+
+		err = UnmarshalDocument([]byte(`{"data":{"type":"tags","relationships":{"owner":{"data":{"type":"owners","id":"1"}}}}}`), &jsonapiTag{})
+		errs = err.(Errors)
+		Expect(errs.HTTPStatus()).To(Equal(http.StatusUnprocessableEntity))
+		Expect(errs.Err[0].Source.Pointer).To(Equal("/data/relationships/owner/data"))
+	})
+
+	It("rejects unknown include paths", func() {
+		_, err := MarshalDocument(simba, DocumentOptions{Include: []string{"owner.pets", "owner.toys"}})
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusBadRequest))
+		Expect(err.(*Error).Source.Parameter).To(Equal("include"))
+		Expect(err.(*Error).Detail).To(ContainSubstring(`"owner.toys"`))
+
+		_, err = MarshalDocument([]*jsonapiPet{}, DocumentOptions{Include: []string{"toys"}})
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusBadRequest))
+	})
+
+	It("works end-to-end with a Resource", func() {
+		source := &jsonapiPetSource{pets: map[string]*jsonapiPet{}}
+
+		e := Endpoint{
+			Method: "POST",
+			Path:   "/pets",
+			Implementation: func(ctx context.Context, r *Req) {
+				res := NewResource(r, source)
+				c, err := res.HandleCreate(ctx, jsonapiPetParser{})
+				if err != nil {
+					res.HandleError(err)
+					return
+				}
+
+				if err := res.Send(c, marshaller{body: c.Value(petCtxKey), status: http.StatusCreated}); err != nil {
+					res.HandleError(err)
+				}
+			},
+		}
+
+		body := `{"data":{"type":"pets","attributes":{"name":"kiara"},"relationships":{"owner":{"data":{"type":"owners","id":"7"}}}}}`
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/pets?include=owner", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", JSONAPIMediaType)
+		req.Header.Set("Accept", JSONAPIMediaType)
+		e.ServeHTTP(res, req)
+
+		Expect(res.Code).To(Equal(http.StatusCreated))
+		Expect(res.Header().Get("Content-Type")).To(Equal(JSONAPIMediaType))
+		Expect(res.Body.Bytes()).To(MatchJSON(`{
+			"data":{"type":"pets","id":"2","attributes":{"name":"kiara"},"relationships":{"owner":{"data":{"type":"owners","id":"7"}}},"links":{"self":"/pets/2"}},
+			"included":[{"type":"owners","id":"7","attributes":{"name":"mufasa"},"relationships":{"best-friend":{"data":null},"pets":{"data":[]}}}]
+		}`))
+	})
+})
@@ -0,0 +1,610 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// MemorySource is a thread-safe DataSource keeping models in memory,
+// for tests and prototypes.
+//
+// Models are structs, identified by their field tagged with
+// `jsonapi:"primary,type"`, or else by their "id" JSON field or ID field.
+// Ids are generated on Create when empty.
+// Fields are filtered and sorted by their JSON names.
+// Models are deep copied in and out of the source, but for their
+// unexported fields.
+//
+// Models with a signed integer Version field get optimistic concurrency:
+// updating a model with a stale non-zero Version is a 409 Conflict.
+// Conditional updates and deletes are atomic, see CompareAndSwapper.
+//
+// Models can be soft deleted, see SoftDeleter.
+// Models with a time.Time or *time.Time DeletedAt field
+// get the time they were soft deleted.
+type MemorySource struct {
+	// Cursors makes FindAll return cursors of next pages
+	// instead of relying on offsets.
+	Cursors bool
+
+	mu       sync.RWMutex
+	typ      reflect.Type
+	id       []int
+	version  []int
+	deleted  []int
+	fields   map[string][]int
+	models   map[string]reflect.Value
+	versions map[string]int
+	trash    map[string]bool
+	order    []string
+	next     int
+}
+
+// NewMemorySource returns an empty MemorySource of models
+// of the type of model, i.e. Pet{}.
+func NewMemorySource(model interface{}) *MemorySource {
+	t := bodyType(model)
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("api: MemorySource expects a struct model, not %T", model))
+	}
+
+	s := &MemorySource{
+		typ:      t,
+		fields:   map[string][]int{},
+		models:   map[string]reflect.Value{},
+		versions: map[string]int{},
+		trash:    map[string]bool{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, _ := parseJSONTag(f.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.fields[name] = f.Index
+
+		if f.Name == "Version" {
+			switch f.Type.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				s.version = f.Index
+			}
+		}
+		if f.Name == "DeletedAt" && (f.Type == timeType || f.Type == reflect.PtrTo(timeType)) {
+			s.deleted = f.Index
+		}
+	}
+
+	if s.id = idIndex(t); s.id == nil {
+		panic(fmt.Sprintf("api: MemorySource model %s has no id field", t))
+	}
+
+	return s
+}
+
+// FindOne finds the model of the id of the Query.
+// Soft deleted models are only found with Query.IncludeDeleted.
+func (s *MemorySource) FindOne(ctx context.Context) (context.Context, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := IDFrom(ctx)
+	m, ok := s.models[id]
+	if !ok || (s.trash[id] && !QueryFrom(ctx).IncludeDeleted) {
+		return ctx, s.notFound(id)
+	}
+
+	return WithResult(ctx, Result{Data: s.copy(m).Addr().Interface()}), nil
+}
+
+// FindAll finds the models matching the filters of the Query,
+// in the order of its sort, or else in the order they were created,
+// and returns the page of the Query with the Total of models.
+// Soft deleted models are only found with Query.IncludeDeleted.
+func (s *MemorySource) FindAll(ctx context.Context) (context.Context, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	q := QueryFrom(ctx)
+
+	matches := []reflect.Value{}
+	for _, id := range s.order {
+		if s.trash[id] && !q.IncludeDeleted {
+			continue
+		}
+
+		m := s.models[id]
+		ok, err := s.match(m, q.Filters)
+		if err != nil {
+			return ctx, err
+		}
+		if ok {
+			matches = append(matches, m)
+		}
+	}
+
+	if err := s.sort(matches, q.Sort); err != nil {
+		return ctx, err
+	}
+
+	total := len(matches)
+	start := q.Page.Offset
+	if q.Page.Cursor != "" {
+		start = total
+		for i, m := range matches {
+			if s.idOf(m) == q.Page.Cursor {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if start > total {
+		start = total
+	}
+
+	end := total
+	if q.Page.Limit > 0 && start+q.Page.Limit < total {
+		end = start + q.Page.Limit
+	}
+
+	data := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(s.typ)), 0, end-start)
+	for _, m := range matches[start:end] {
+		data = reflect.Append(data, s.copy(m).Addr())
+	}
+
+	result := Result{Data: data.Interface(), Total: &total}
+	if s.Cursors && end < total && end > start {
+		result.NextCursor = s.idOf(matches[end-1])
+	}
+
+	return WithResult(ctx, result), nil
+}
+
+// Create adds the model of the context, see WithModel,
+// and returns a context with its id.
+// Creating a model with the id of another one is a 409 Conflict.
+func (s *MemorySource) Create(ctx context.Context) (context.Context, error) {
+	m, err := s.model(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.idOf(m)
+	if id == "" {
+		for taken := true; taken; _, taken = s.models[id] {
+			s.next++
+			id = strconv.Itoa(s.next)
+		}
+		if err := setValue(m.FieldByIndex(s.id), id); err != nil {
+			return ctx, WrapErr(err, http.StatusInternalServerError)
+		}
+	} else if _, ok := s.models[id]; ok {
+		return ctx, s.conflict(fmt.Sprintf("%s %s already exists", s.typ.Name(), id))
+	}
+
+	s.versions[id] = 1
+	s.setVersion(m, 1)
+	s.models[id] = s.copy(m)
+	s.order = append(s.order, id)
+
+	return WithID(ctx, id), nil
+}
+
+// Update replaces the model of the id of the Query
+// with the model of the context, see WithModel.
+func (s *MemorySource) Update(ctx context.Context) (context.Context, error) {
+	return s.UpdateIf(ctx, "*")
+}
+
+// UpdateIf updates like Update, only while the ETag
+// of the model matches an If-Match header.
+func (s *MemorySource) UpdateIf(ctx context.Context, ifMatch string) (context.Context, error) {
+	m, err := s.model(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := IDFrom(ctx)
+	if err := s.checkIfMatch(id, ifMatch); err != nil {
+		return ctx, err
+	}
+
+	if s.version != nil {
+		if v := int(m.FieldByIndex(s.version).Int()); v != 0 && v != s.versions[id] {
+			return ctx, s.conflict(fmt.Sprintf("%s %s is at version %d, not %d", s.typ.Name(), id, s.versions[id], v))
+		}
+	}
+
+	if err := setValue(m.FieldByIndex(s.id), id); err != nil {
+		return ctx, WrapErr(err, http.StatusInternalServerError)
+	}
+
+	s.versions[id]++
+	s.setVersion(m, s.versions[id])
+	s.models[id] = s.copy(m)
+
+	return ctx, nil
+}
+
+// Delete removes the model of the id of the Query.
+func (s *MemorySource) Delete(ctx context.Context) (context.Context, error) {
+	return s.DeleteIf(ctx, "*")
+}
+
+// DeleteIf deletes like Delete, only while the ETag
+// of the model matches an If-Match header.
+func (s *MemorySource) DeleteIf(ctx context.Context, ifMatch string) (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := IDFrom(ctx)
+	if err := s.checkIfMatch(id, ifMatch); err != nil {
+		return ctx, err
+	}
+
+	delete(s.models, id)
+	delete(s.versions, id)
+	delete(s.trash, id)
+	for i, o := range s.order {
+		if o == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+
+	return ctx, nil
+}
+
+// SoftDelete marks the model of the id of the Query as deleted.
+func (s *MemorySource) SoftDelete(ctx context.Context) (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := IDFrom(ctx)
+	if err := s.checkIfMatch(id, "*"); err != nil {
+		return ctx, err
+	}
+
+	s.trash[id] = true
+	s.setDeletedAt(id, time.Now())
+	return ctx, nil
+}
+
+// Restore unmarks the model of the id of the Query, soft deleted or not.
+func (s *MemorySource) Restore(ctx context.Context) (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := IDFrom(ctx)
+	if _, ok := s.models[id]; !ok {
+		return ctx, s.notFound(id)
+	}
+
+	if s.trash[id] {
+		delete(s.trash, id)
+		s.setDeletedAt(id, time.Time{})
+	}
+	return ctx, nil
+}
+
+// setDeletedAt sets the DeletedAt field of a model, if any,
+// with a new version.
+func (s *MemorySource) setDeletedAt(id string, at time.Time) {
+	m := s.models[id]
+	s.versions[id]++
+	s.setVersion(m, s.versions[id])
+
+	if s.deleted == nil {
+		return
+	}
+
+	f := m.FieldByIndex(s.deleted)
+	switch {
+	case f.Type() == timeType:
+		f.Set(reflect.ValueOf(at))
+	case at.IsZero():
+		f.Set(reflect.Zero(f.Type()))
+	default:
+		f.Set(reflect.ValueOf(&at))
+	}
+}
+
+// checkIfMatch checks that a model exists, is not soft deleted,
+// and matches an If-Match header.
+func (s *MemorySource) checkIfMatch(id, ifMatch string) error {
+	m, ok := s.models[id]
+	if !ok || s.trash[id] {
+		return s.notFound(id)
+	}
+	if ifMatch == "*" {
+		return nil
+	}
+
+	etag, err := ETag(m.Addr().Interface())
+	if err != nil {
+		return err
+	}
+	if !MatchETag(ifMatch, etag) {
+		return preconditionFailed()
+	}
+	return nil
+}
+
+// model returns the addressable model of the context.
+func (s *MemorySource) model(ctx context.Context) (reflect.Value, error) {
+	v := reflect.ValueOf(ModelFrom(ctx))
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type() == s.typ {
+		return v.Elem(), nil
+	}
+	if v.IsValid() && v.Type() == s.typ {
+		m := reflect.New(s.typ).Elem()
+		m.Set(v)
+		return m, nil
+	}
+	return reflect.Value{}, NewError(http.StatusInternalServerError, fmt.Sprintf("api: MemorySource expects a %s model, not %T", s.typ, ModelFrom(ctx)))
+}
+
+// copy returns an addressable deep copy of a model,
+// so that callers cannot change the stored models through their
+// slices, maps and pointers.
+func (s *MemorySource) copy(m reflect.Value) reflect.Value {
+	c := reflect.New(s.typ).Elem()
+	c.Set(deepCopy(m))
+	return c
+}
+
+// deepCopy copies a value along with the pointers, slices and maps it holds.
+// Unexported fields are copied as is, and cycles are not supported.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), deepCopy(it.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+func (s *MemorySource) idOf(m reflect.Value) string {
+	id := indirect(m.FieldByIndex(s.id))
+	if !id.IsValid() || isZero(id) {
+		return ""
+	}
+	return valueString(id)
+}
+
+func (s *MemorySource) setVersion(m reflect.Value, version int) {
+	if s.version != nil {
+		m.FieldByIndex(s.version).SetInt(int64(version))
+	}
+}
+
+// field returns the field of a model from its JSON name.
+func (s *MemorySource) field(m reflect.Value, name, param string) (reflect.Value, error) {
+	idx, ok := s.fields[name]
+	if !ok {
+		return reflect.Value{}, queryError(param, "has an unknown field "+name)
+	}
+	return m.FieldByIndex(idx), nil
+}
+
+// match reports whether a model matches all filters.
+func (s *MemorySource) match(m reflect.Value, filters []Filter) (bool, error) {
+	for _, f := range filters {
+		param := "filter[" + f.Field + "][" + string(f.Op) + "]"
+		v, err := s.field(m, f.Field, param)
+		if err != nil {
+			return false, err
+		}
+
+		ok, err := matchFilter(v, f)
+		if err != nil {
+			return false, queryError(param, err.Error())
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchFilter(v reflect.Value, f Filter) (bool, error) {
+	if f.Op == OpNull {
+		isNull := (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface || v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil()
+		return isNull == (f.Values[0] == "true"), nil
+	}
+
+	v = indirect(v)
+	if !v.IsValid() {
+		return f.Op == OpNe, nil
+	}
+
+	if f.Op == OpLike {
+		expr := "^" + strings.Replace(regexp.QuoteMeta(f.Values[0]), "%", ".*", -1) + "$"
+		return compileRegexp(expr).MatchString(valueString(v)), nil
+	}
+
+	for _, value := range f.Values {
+		c, err := compareValue(v, value)
+		if err != nil {
+			return false, err
+		}
+
+		switch f.Op {
+		case OpEq, OpIn:
+			if c == 0 {
+				return true, nil
+			}
+		case OpNe:
+			return c != 0, nil
+		case OpLt:
+			return c < 0, nil
+		case OpGt:
+			return c > 0, nil
+		}
+	}
+	return false, nil
+}
+
+// compareValue compares a field to the string representation of a value.
+func compareValue(v reflect.Value, s string) (int, error) {
+	other := reflect.New(v.Type()).Elem()
+	if err := setValue(other, s); err != nil {
+		return 0, err
+	}
+	return compareValues(v, other), nil
+}
+
+// compareValues compares two values of the same type.
+func compareValues(a, b reflect.Value) int {
+	a, b = indirect(a), indirect(b)
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0
+	case !a.IsValid():
+		return -1
+	case !b.IsValid():
+		return 1
+	}
+
+	if a.Type() == timeType {
+		ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1
+		case ta.After(tb):
+			return 1
+		}
+		return 0
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int() < b.Int(), a.Int() > b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint() < b.Uint(), a.Uint() > b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float() < b.Float(), a.Float() > b.Float())
+	case reflect.Bool:
+		return compareOrdered(!a.Bool() && b.Bool(), a.Bool() && !b.Bool())
+	}
+	return strings.Compare(valueString(a), valueString(b))
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// sort orders models by fields.
+func (s *MemorySource) sort(models []reflect.Value, fields []SortField) error {
+	for _, f := range fields {
+		if _, ok := s.fields[f.Field]; !ok {
+			return queryError("sort", "has an unknown field "+f.Field)
+		}
+	}
+
+	sort.SliceStable(models, func(i, j int) bool {
+		for _, f := range fields {
+			idx := s.fields[f.Field]
+			c := compareValues(models[i].FieldByIndex(idx), models[j].FieldByIndex(idx))
+			if c != 0 {
+				return (c < 0) != f.Desc
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func (s *MemorySource) notFound(id string) *Error {
+	return notFoundError(s.typ, id)
+}
+
+func notFoundError(t reflect.Type, id string) *Error {
+	err := NewError(http.StatusNotFound, "Not found")
+	err.Code = "not_found"
+	err.Detail = fmt.Sprintf("%s %s does not exist", t.Name(), id)
+	return err
+}
+
+func (s *MemorySource) conflict(detail string) *Error {
+	return conflictError(detail)
+}
+
+func conflictError(detail string) *Error {
+	err := NewError(http.StatusConflict, "Conflict")
+	err.Code = "conflict"
+	err.Detail = detail
+	return err
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type memoryPet struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Age     int        `json:"age"`
+	Born    time.Time  `json:"born"`
+	Owner   *string    `json:"owner"`
+	Version int        `json:"version"`
+	Seen    *time.Time `json:"-"`
+}
+
+type memoryLitter struct {
+	ID        string            `json:"id"`
+	Names     []string          `json:"names"`
+	Parents   map[string]string `json:"parents"`
+	DeletedAt *time.Time        `json:"deleted_at"`
+}
+
+var _ = Describe("MemorySource", func() {
+	var source *MemorySource
+
+	create := func(p memoryPet) string {
+		ctx, err := source.Create(WithModel(context.Background(), &p))
+		Expect(err).ToNot(HaveOccurred())
+		return IDFrom(ctx)
+	}
+
+	findAll := func(q Query) ([]*memoryPet, *int, error) {
+		ctx, err := source.FindAll(WithQuery(context.Background(), q))
+		if err != nil {
+			return nil, nil, err
+		}
+		r := ResultFrom(ctx)
+		return r.Data.([]*memoryPet), r.Total, nil
+	}
+
+	names := func(pets []*memoryPet) (names []string) {
+		for _, p := range pets {
+			names = append(names, p.Name)
+		}
+		return
+	}
+
+	BeforeEach(func() {
+		source = NewMemorySource(memoryPet{})
+
+		mufasa := "mufasa"
+		create(memoryPet{Name: "simba", Age: 3, Born: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), Owner: &mufasa})
+		create(memoryPet{Name: "nala", Age: 3, Born: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)})
+		create(memoryPet{Name: "kiara", Age: 1, Born: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})
+	})
+
+	It("supports the lifecycle of a Resource", func() {
+		ctx, err := source.FindOne(WithID(context.Background(), "2"))
+		Expect(err).ToNot(HaveOccurred())
+
+		nala := ResultFrom(ctx).Data.(*memoryPet)
+		Expect(nala.Name).To(Equal("nala"))
+		Expect(nala.Version).To(Equal(1))
+
+		// Results are copies.
+		nala.Name = "sarabi"
+		nala.Version = 0
+		ctx, _ = source.FindOne(WithID(context.Background(), "2"))
+		Expect(ResultFrom(ctx).Data.(*memoryPet).Name).To(Equal("nala"))
+
+		_, err = source.Update(WithModel(WithID(context.Background(), "2"), nala))
+		Expect(err).ToNot(HaveOccurred())
+		ctx, _ = source.FindOne(WithID(context.Background(), "2"))
+		Expect(ResultFrom(ctx).Data).To(Equal(&memoryPet{ID: "2", Name: "sarabi", Age: 3, Born: nala.Born, Version: 2}))
+
+		_, err = source.Delete(WithID(context.Background(), "2"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = source.FindOne(WithID(context.Background(), "2"))
+		Expect(err.(*Error).Status).To(Equal("404"))
+		_, err = source.Update(WithModel(WithID(context.Background(), "2"), nala))
+		Expect(err.(*Error).Status).To(Equal("404"))
+		_, err = source.Delete(WithID(context.Background(), "2"))
+		Expect(err.(*Error).Status).To(Equal("404"))
+
+		Expect(create(memoryPet{Name: "rafiki"})).To(Equal("4"))
+		Expect(create(memoryPet{ID: "zazu", Name: "zazu"})).To(Equal("zazu"))
+		_, err = source.Create(WithModel(context.Background(), &memoryPet{ID: "zazu"}))
+		Expect(err.(*Error).Status).To(Equal("409"))
+	})
+
+	It("rejects stale versions", func() {
+		_, err := source.Update(WithModel(WithID(context.Background(), "1"), &memoryPet{Name: "simba", Version: 1}))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = source.Update(WithModel(WithID(context.Background(), "1"), &memoryPet{Name: "simba", Version: 1}))
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*Error).Status).To(Equal("409"))
+		Expect(err.(*Error).Detail).To(Equal("memoryPet 1 is at version 2, not 1"))
+	})
+
+	It("filters and sorts", func() {
+		pets, total, err := findAll(Query{Sort: []SortField{{Field: "age", Desc: true}, {Field: "name"}}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(pets)).To(Equal([]string{"nala", "simba", "kiara"}))
+		Expect(*total).To(Equal(3))
+
+		for _, c := range []struct {
+			filter   Filter
+			expected []string
+		}{
+			{Filter{Field: "age", Op: OpEq, Values: []string{"3"}}, []string{"simba", "nala"}},
+			{Filter{Field: "age", Op: OpNe, Values: []string{"3"}}, []string{"kiara"}},
+			{Filter{Field: "age", Op: OpLt, Values: []string{"3"}}, []string{"kiara"}},
+			{Filter{Field: "born", Op: OpGt, Values: []string{"2018-06-01T00:00:00Z"}}, []string{"simba", "kiara"}},
+			{Filter{Field: "name", Op: OpIn, Values: []string{"kiara", "nala"}}, []string{"nala", "kiara"}},
+			{Filter{Field: "name", Op: OpLike, Values: []string{"%a"}}, []string{"simba", "nala", "kiara"}},
+			{Filter{Field: "name", Op: OpLike, Values: []string{"n%"}}, []string{"nala"}},
+			{Filter{Field: "owner", Op: OpNull, Values: []string{"true"}}, []string{"nala", "kiara"}},
+			{Filter{Field: "owner", Op: OpNull, Values: []string{"false"}}, []string{"simba"}},
+			{Filter{Field: "owner", Op: OpEq, Values: []string{"mufasa"}}, []string{"simba"}},
+		} {
+			pets, total, err := findAll(Query{Filters: []Filter{c.filter}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(pets)).To(Equal(c.expected), "%v", c.filter)
+			Expect(*total).To(Equal(len(c.expected)))
+		}
+	})
+
+	It("reports unknown fields and invalid values", func() {
+		_, _, err := findAll(Query{Filters: []Filter{{Field: "secret", Op: OpEq, Values: []string{"1"}}}})
+		Expect(err.(*Error).Status).To(Equal("400"))
+
+		_, _, err = findAll(Query{Filters: []Filter{{Field: "age", Op: OpGt, Values: []string{"old"}}}})
+		Expect(err.(*Error).Status).To(Equal("400"))
+		Expect(err.(*Error).Source.Parameter).To(Equal("filter[age][gt]"))
+
+		_, _, err = findAll(Query{Sort: []SortField{{Field: "Seen"}}})
+		Expect(err.(*Error).Status).To(Equal("400"))
+	})
+
+	It("paginates with offsets and cursors", func() {
+		pets, total, _ := findAll(Query{Page: Page{Offset: 1, Limit: 1}})
+		Expect(names(pets)).To(Equal([]string{"nala"}))
+		Expect(*total).To(Equal(3))
+
+		pets, _, _ = findAll(Query{Page: Page{Offset: 5}})
+		Expect(pets).To(BeEmpty())
+
+		source.Cursors = true
+		ctx, _ := source.FindAll(WithQuery(context.Background(), Query{Page: Page{Limit: 2}}))
+		Expect(ResultFrom(ctx).NextCursor).To(Equal("2"))
+
+		ctx, _ = source.FindAll(WithQuery(context.Background(), Query{Page: Page{Limit: 2, Cursor: "2"}}))
+		Expect(names(ResultFrom(ctx).Data.([]*memoryPet))).To(Equal([]string{"kiara"}))
+		Expect(ResultFrom(ctx).NextCursor).To(BeEmpty())
+	})
+
+	It("shares no slices, maps or pointers with the stored models", func() {
+		litters := NewMemorySource(memoryLitter{})
+		deleted := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		litter := &memoryLitter{Names: []string{"kion"}, Parents: map[string]string{"father": "simba"}, DeletedAt: &deleted}
+		_, err := litters.Create(WithModel(context.Background(), litter))
+		Expect(err).ToNot(HaveOccurred())
+
+		litter.Names[0] = "kovu"
+		ctx, err := litters.FindOne(WithQuery(context.Background(), Query{ID: "1", IncludeDeleted: true}))
+		Expect(err).ToNot(HaveOccurred())
+		found := ResultFrom(ctx).Data.(*memoryLitter)
+		Expect(found.Names).To(Equal([]string{"kion"}))
+
+		found.Names[0] = "kovu"
+		found.Parents["father"] = "scar"
+		*found.DeletedAt = time.Time{}
+
+		ctx, _ = litters.FindOne(WithQuery(context.Background(), Query{ID: "1", IncludeDeleted: true}))
+		found = ResultFrom(ctx).Data.(*memoryLitter)
+		Expect(found.Names).To(Equal([]string{"kion"}))
+		Expect(found.Parents).To(Equal(map[string]string{"father": "simba"}))
+		Expect(*found.DeletedAt).To(Equal(deleted))
+	})
+
+	It("is safe for concurrent use", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				create(memoryPet{Name: "hyena"})
+				findAll(Query{})
+			}()
+		}
+		wg.Wait()
+
+		_, total, _ := findAll(Query{})
+		Expect(*total).To(Equal(23))
+	})
+
+	It("serves a Resource", func() {
+		api := New("")
+		api.AddResource("pets", source, ResourceModel(memoryPet{}))
+
+		r, _ := http.NewRequest("GET", "/pets?filter[age]=3&sort=-born&page[limit]=1", nil)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"name":"simba"`))
+		Expect(w.Header().Get("X-Total-Count")).To(Equal("2"))
+
+		r, _ = http.NewRequest("PATCH", "/pets/1", strings.NewReader(`{"name":"simba","version":7}`))
+		w = httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+	})
+})
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// Param declares a query, path or header parameter of an Endpoint.
+type Param struct {
+	Name        string // Name of the parameter, without the leading colon of path parameters
+	In          string // "query" (default), "path" or "header"
+	Description string
+	Required    bool
+
+	// A value of the type of the parameter, i.e. 0 for an integer.
+	// Parameters are strings by default.
+	Type interface{}
+
+	// Constraints checked on each value of the parameter
+	Format    string   // Only "uuid" is enforced
+	Enum      []string // Allowed values
+	Pattern   string   // Regular expression values must match
+	Min       *float64 // Minimum of numeric values
+	Max       *float64 // Maximum of numeric values
+	MinLength int      // Minimum length of values, if not zero
+	MaxLength int      // Maximum length of values, if not zero
+}
+
+// Key returns the key of the parameter in Params.
+func (p Param) Key() string {
+	if p.In == "path" {
+		return ":" + p.Name
+	}
+	return p.Name
+}
+
+// GoType returns the type of the parameter.
+func (p Param) GoType() reflect.Type {
+	if p.Type == nil {
+		return reflect.TypeOf("")
+	}
+	return reflect.TypeOf(p.Type)
+}
+
+// RequestType returns the type of the request body,
+// or nil if the endpoint does not declare one.
+func (e Endpoint) RequestType() reflect.Type {
+	return bodyType(e.Request)
+}
+
+// ResponseTypes returns the type of the response body for each status code.
+// Statuses without a body have a nil type.
+func (e Endpoint) ResponseTypes() map[int]reflect.Type {
+	types := make(map[int]reflect.Type, len(e.Responses))
+	for status, body := range e.Responses {
+		types[status] = bodyType(body)
+	}
+	return types
+}
+
+// Param returns the declared parameter with a name, if any.
+func (e Endpoint) Param(in, name string) (Param, bool) {
+	for _, p := range e.Params {
+		if p.In == in && p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// describe normalizes the metadata of an endpoint:
+// parameters are in the query unless specified otherwise,
+// and path parameters missing from Params are declared as required strings.
+// It panics when the metadata is inconsistent with the path,
+// or when a Pattern does not compile, since this is a programming error.
+func (e *Endpoint) describe() {
+	names := map[string]bool{}
+	for _, name := range pathParams(e.Path) {
+		names[name] = true
+	}
+
+	params := make([]Param, 0, len(e.Params)+len(names))
+	for _, p := range e.Params {
+		if p.In == "" {
+			p.In = "query"
+		}
+
+		switch p.In {
+		case "query", "header":
+		case "path":
+			if !names[p.Name] {
+				panic(fmt.Sprintf("api: %s %s declares path parameter %q missing from its path", e.Method, e.Path, p.Name))
+			}
+			p.Required = true
+		default:
+			panic(fmt.Sprintf("api: %s %s declares parameter %q in unknown location %q", e.Method, e.Path, p.Name, p.In))
+		}
+
+		if p.Pattern != "" {
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				panic(fmt.Sprintf("api: %s %s declares parameter %q with an invalid pattern: %v", e.Method, e.Path, p.Name, err))
+			}
+			regexps.Store(p.Pattern, re)
+		}
+
+		params = append(params, p)
+	}
+
+	for _, name := range pathParams(e.Path) {
+		if _, ok := e.Param("path", name); !ok {
+			params = append(params, Param{Name: name, In: "path", Required: true})
+		}
+	}
+
+	e.Params = params
+}
+
+func bodyType(v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
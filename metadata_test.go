@@ -0,0 +1,101 @@
+package api
+
+import (
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Endpoint metadata", func() {
+	noop := func(ctx context.Context, r *Req) {}
+
+	It("declares path parameters when adding an endpoint", func() {
+		api := New("/v1")
+		api.Add(Endpoint{
+			Method: "GET",
+			Path:   "/owners/:owner_id/pets/:id",
+			Params: []Param{
+				{Name: "limit", Type: 0},
+				{Name: "id", In: "path", Description: "The pet id"},
+			},
+			Implementation: noop,
+		})
+
+		e := api.Endpoints[0]
+		Expect(e.Params).To(HaveLen(3))
+
+		limit, ok := e.Param("query", "limit")
+		Expect(ok).To(BeTrue())
+		Expect(limit.Key()).To(Equal("limit"))
+		Expect(limit.GoType().Kind()).To(Equal(reflect.Int))
+
+		id, ok := e.Param("path", "id")
+		Expect(ok).To(BeTrue())
+		Expect(id.Key()).To(Equal(":id"))
+		Expect(id.Required).To(BeTrue())
+		Expect(id.Description).To(Equal("The pet id"))
+
+		owner, ok := e.Param("path", "owner_id")
+		Expect(ok).To(BeTrue())
+		Expect(owner.Required).To(BeTrue())
+		Expect(owner.GoType().Kind()).To(Equal(reflect.String))
+	})
+
+	It("rejects path parameters missing from the path", func() {
+		api := New("/v1")
+		Expect(func() {
+			api.Add(Endpoint{
+				Method:         "GET",
+				Path:           "/pets",
+				Params:         []Param{{Name: "id", In: "path"}},
+				Implementation: noop,
+			})
+		}).To(Panic())
+	})
+
+	It("rejects invalid patterns", func() {
+		api := New("/v1")
+		Expect(func() {
+			api.Add(Endpoint{
+				Method:         "GET",
+				Path:           "/pets",
+				Params:         []Param{{Name: "name", Pattern: "[a-z"}},
+				Implementation: noop,
+			})
+		}).To(Panic())
+	})
+
+	It("introspects the body types", func() {
+		e := Endpoint{
+			Request:   &pet{},
+			Responses: map[int]interface{}{200: []pet{}, 204: nil},
+		}
+
+		Expect(e.RequestType()).To(Equal(reflect.TypeOf(pet{})))
+		Expect(e.ResponseTypes()[200]).To(Equal(reflect.TypeOf([]pet{})))
+		Expect(e.ResponseTypes()).To(HaveKey(204))
+		Expect(e.ResponseTypes()[204]).To(BeNil())
+		Expect(Endpoint{}.RequestType()).To(BeNil())
+	})
+
+	It("documents parameters and deprecation", func() {
+		api := New("/v1")
+		api.Add(Endpoint{
+			Method:         "GET",
+			Path:           "/pets/:id",
+			Deprecated:     true,
+			Params:         []Param{{Name: "X-Token", In: "header", Required: true}, {Name: "fields", Type: []string{}}},
+			Implementation: noop,
+		})
+
+		op := api.OpenAPI(OpenAPIInfo{Title: "pets", Version: "1"}).Paths["/v1/pets/{id}"]["get"]
+		Expect(op.Deprecated).To(BeTrue())
+		Expect(op.Parameters).To(HaveLen(3))
+		Expect(op.Parameters[0].In).To(Equal("header"))
+		Expect(op.Parameters[0].Required).To(BeTrue())
+		Expect(op.Parameters[1].Schema.Type).To(Equal("array"))
+		Expect(op.Parameters[2].Name).To(Equal("id"))
+	})
+})
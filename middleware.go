@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"reflect"
 	"runtime"
 
@@ -9,7 +10,6 @@ import (
 
 type Middleware interface {
 
-	// TODO: wrapping middleware (i.e. for logging)
 	// HandlerFunc to process the incoming request and
 	// returns a http error code and error message if needed.
 	Run(ctx context.Context, r *Req) (context.Context, error)
@@ -31,3 +31,58 @@ func (m MiddlewareFunc) Name() string {
 }
 
 type MiddlewareStack []Middleware
+
+// Then returns a Handler running each middleware of the stack
+// in order before calling h.
+// The first middleware returning an error writes it to the response
+// and stops the chain.
+func (s MiddlewareStack) Then(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, r *Req) {
+		for _, m := range s {
+			c, err := m.Run(ctx, r)
+			if err != nil {
+				er := WrapErr(err, 0)
+				r.Response.WriteHeader(er.HTTPStatus())
+				fmt.Fprintln(r.Response, er.HTTPBody())
+				return
+			}
+			ctx = c
+		}
+
+		h.Serve(ctx, r)
+	})
+}
+
+// Wrapper is a wrapping (around-style) middleware.
+// It receives the next Handler in the chain so it can run code
+// before and after it (i.e. for timing, logging or transactions).
+type Wrapper interface {
+	// Wrap returns a Handler calling next.
+	Wrap(next Handler) Handler
+
+	// Name of the wrapper for debugging
+	Name() string
+}
+
+// WrapperFunc transforms a function with the right signature
+// into a Wrapper
+type WrapperFunc func(next Handler) Handler
+
+func (w WrapperFunc) Wrap(next Handler) Handler {
+	return w(next)
+}
+
+func (w WrapperFunc) Name() string {
+	return runtime.FuncForPC(reflect.ValueOf(w).Pointer()).Name()
+}
+
+// WrapperStack is a list of wrappers, the first one being the outermost.
+type WrapperStack []Wrapper
+
+// Then wraps h with each wrapper of the stack.
+func (s WrapperStack) Then(h Handler) Handler {
+	for i := len(s) - 1; i >= 0; i-- {
+		h = s[i].Wrap(h)
+	}
+	return h
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"golang.org/x/net/context"
@@ -18,4 +21,85 @@ var _ = Describe("Middleware", func() {
 			Expect(f.Name()).To(ContainSubstring("github.com/olivoil"))
 		})
 	})
+
+	Context("WrapperFunc", func() {
+		It("find a Name", func() {
+			wrapper := func(next Handler) Handler {
+				return next
+			}
+
+			f := WrapperFunc(wrapper)
+
+			Expect(f.Name()).To(ContainSubstring("github.com/olivoil"))
+		})
+
+		It("runs around the middleware stack and the implementation", func() {
+			calls := []string{}
+
+			trace := func(name string) Wrapper {
+				return WrapperFunc(func(next Handler) Handler {
+					return HandlerFunc(func(ctx context.Context, r *Req) {
+						calls = append(calls, "before "+name)
+						next.Serve(ctx, r)
+						calls = append(calls, "after "+name)
+					})
+				})
+			}
+
+			e := Endpoint{
+				Method: "GET",
+				Path:   "/pets",
+				Middleware: MiddlewareStack{MiddlewareFunc(func(ctx context.Context, r *Req) (context.Context, error) {
+					calls = append(calls, "middleware")
+					return ctx, nil
+				})},
+				Implementation: func(ctx context.Context, r *Req) {
+					calls = append(calls, "implementation")
+					r.NoContent(http.StatusNoContent)
+				},
+			}
+			e.Wrap(trace("outer"))
+			e.Wrap(trace("inner"))
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/pets", nil)
+			e.ServeHTTP(res, req)
+
+			Expect(res.Code).To(Equal(http.StatusNoContent))
+			Expect(calls).To(Equal([]string{
+				"before outer",
+				"before inner",
+				"middleware",
+				"implementation",
+				"after inner",
+				"after outer",
+			}))
+		})
+
+		It("sees the response of a failing middleware", func() {
+			var status int
+
+			e := Endpoint{
+				Method:     "GET",
+				Path:       "/pets",
+				Middleware: MiddlewareStack{MiddlewareFunc(auth)},
+				Wrappers: WrapperStack{WrapperFunc(func(next Handler) Handler {
+					return HandlerFunc(func(ctx context.Context, r *Req) {
+						next.Serve(ctx, r)
+						status = r.ResponseStatus()
+					})
+				})},
+				Implementation: func(ctx context.Context, r *Req) {
+					r.NoContent(http.StatusNoContent)
+				},
+			}
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/pets", nil)
+			e.ServeHTTP(res, req)
+
+			Expect(res.Code).To(Equal(http.StatusUnauthorized))
+			Expect(status).To(Equal(http.StatusUnauthorized))
+		})
+	})
 })
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// ServeHTTP implements the http.Handler interface so an API can be served
+// without any router library, i.e. http.ListenAndServe(":8080", api).
+// Paths are matched against the prefixed endpoint paths
+// with the same :name and *name parameters httprouter understands.
+// As with httprouter, the value of a *name parameter starts with a "/".
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		match  *Endpoint
+		params Values
+		best   = -1
+		verbs  []string
+	)
+
+	for i, e := range api.Endpoints {
+		ps, score, ok := matchPath(api.Prefix+e.Path, r.URL.Path)
+		if !ok {
+			continue
+		}
+
+		// Collect the methods of every path matching the request,
+		// so we know what to allow.
+		if verbs == nil {
+			verbs = []string{"OPTIONS"}
+		}
+		if !contains(verbs, e.Method) {
+			verbs = append(verbs, e.Method)
+		}
+
+		// Keep the most specific path matching the request and its method.
+		if e.Method == r.Method && score > best {
+			best = score
+			match = &api.Endpoints[i]
+			params = ps
+		}
+	}
+
+	if match != nil {
+		req := NewReq(w, r, &Params{Path: params})
+		api.handler(*match).Serve(context.Background(), req)
+		return
+	}
+
+	req := WrapReq(w, r)
+
+	if verbs == nil {
+		HandleError(req, NewError(http.StatusNotFound, http.StatusText(http.StatusNotFound)))
+		return
+	}
+
+	if r.Method == "OPTIONS" {
+		optionsHandler(verbs).Serve(context.Background(), req)
+		return
+	}
+
+	req.Response.Header().Set("Allow", strings.Join(verbs, ","))
+	HandleError(req, NewError(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed)))
+}
+
+// matchPath matches a request path against an endpoint path.
+// It returns the path parameters keyed by their :name
+// and the number of static segments matched, to rank routes.
+func matchPath(pattern, path string) (Values, int, bool) {
+	segments := splitPath(pattern)
+	parts := splitPath(path)
+	params := Values{}
+	score := 0
+
+	for i, s := range segments {
+		if strings.HasPrefix(s, "*") {
+			rest := ""
+			if i < len(parts) {
+				rest = strings.Join(parts[i:], "/")
+			}
+			if rest != "" && strings.HasSuffix(path, "/") {
+				rest += "/"
+			}
+			params[":"+s[1:]] = splitValues([]string{catchAllValue(rest)}, ",")
+			return params, score, true
+		}
+
+		if i >= len(parts) {
+			return nil, 0, false
+		}
+
+		if strings.HasPrefix(s, ":") {
+			if parts[i] == "" {
+				return nil, 0, false
+			}
+			params[":"+s[1:]] = splitValues([]string{parts[i]}, ",")
+			continue
+		}
+
+		if s != parts[i] {
+			return nil, 0, false
+		}
+		score++
+	}
+
+	if len(parts) != len(segments) {
+		return nil, 0, false
+	}
+
+	return params, score, true
+}
+
+// pathParams returns the names of the parameters of an endpoint path.
+func pathParams(pattern string) []string {
+	names := []string{}
+	for _, s := range splitPath(pattern) {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			names = append(names, s[1:])
+		}
+	}
+	return names
+}
+
+// catchAllValue returns the value of a *name parameter
+// in the form httprouter gives it, starting with a "/".
+func catchAllValue(v string) string {
+	return "/" + strings.TrimPrefix(v, "/")
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+// Adapter for net/http ServeMux, using the method and wildcard
+// patterns introduced in go 1.22.
+type serveMuxAdapter struct {
+	*http.ServeMux
+}
+
+func (router *serveMuxAdapter) Add(method, path string, h Handler) {
+	router.HandleFunc(method+" "+serveMuxPattern(path), func(w http.ResponseWriter, r *http.Request) {
+		params := PathParams(path, func(name string, catchAll bool) string {
+			return r.PathValue(name)
+		})
+
+		h.Serve(context.Background(), NewReq(w, r, params))
+	})
+}
+
+// serveMuxPattern converts an endpoint path to a ServeMux pattern,
+// i.e. /users/:id/*path becomes /users/{id}/{path...}
+func serveMuxPattern(path string) string {
+	pattern := RewritePath(path, func(name string) string {
+		return "{" + name + "}"
+	}, func(name string) string {
+		return "{" + name + "...}"
+	})
+
+	// A trailing slash would match every path below it.
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+
+	return pattern
+}
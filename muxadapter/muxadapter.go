@@ -0,0 +1,49 @@
+// Package muxadapter lets an API be activated on a github.com/gorilla/mux
+// router. Importing it registers the adapter, i.e.
+//
+//	import _ "github.com/olivoil/api.v2/muxadapter"
+//
+//	router := mux.NewRouter()
+//	api.Activate(router)
+package muxadapter
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	api "github.com/olivoil/api.v2"
+	"golang.org/x/net/context"
+)
+
+// Adapter for github.com/gorilla/mux
+type adapter struct {
+	*mux.Router
+}
+
+func (router *adapter) Add(method, path string, h api.Handler) {
+	router.Handle(Pattern(path), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		params := api.PathParams(path, func(name string, catchAll bool) string {
+			return vars[name]
+		})
+
+		h.Serve(context.Background(), api.NewReq(w, r, params))
+	})).Methods(method)
+}
+
+// Pattern converts an endpoint path to a gorilla/mux pattern,
+// i.e. /users/:id/*path becomes /users/{id}/{path:.*}
+func Pattern(path string) string {
+	return api.RewritePath(path, func(name string) string {
+		return "{" + name + "}"
+	}, func(name string) string {
+		return "{" + name + ":.*}"
+	})
+}
+
+func init() {
+	api.RegisterRouter("gorilla/mux", func(v interface{}) (api.Router, bool) {
+		r, ok := v.(*mux.Router)
+		return &adapter{r}, ok
+	})
+}
@@ -0,0 +1,13 @@
+package muxadapter
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMuxadapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "muxadapter tests")
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A RequestEncoder is an Encoder depending on the request it responds to,
+// i.e. to honor the include parameter of jsonapi.org.
+type RequestEncoder interface {
+	Encoder
+	EncodeRequest(req *Req, v interface{}) ([]byte, error)
+}
+
+// A ModelEncoder is an Encoder of some values only,
+// i.e. of models tagged for jsonapi.org.
+// Negotiation skips it for the values it cannot encode.
+type ModelEncoder interface {
+	Encoder
+	CanEncode(v interface{}) bool
+}
+
+type mediaEncoder struct {
+	mediaType string
+	encoder   Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   []mediaEncoder
+)
+
+// RegisterEncoder registers the Encoder of a media type, replacing
+// the previous one if any.
+// When a client accepts several media types equally,
+// the first registered is preferred.
+func RegisterEncoder(mediaType string, e Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	for i, me := range encoders {
+		if me.mediaType == mediaType {
+			encoders[i].encoder = e
+			return
+		}
+	}
+
+	encoders = append(encoders, mediaEncoder{mediaType: mediaType, encoder: e})
+}
+
+// Negotiate picks the Encoder of the media type preferred by an Accept header.
+// An empty header accepts any media type.
+// It returns false when none of the registered media types is acceptable.
+func Negotiate(accept string) (string, Encoder, bool) {
+	return negotiate(accept, nil)
+}
+
+// negotiate picks the preferred Encoder of the media types able to
+// encode a body. A nil body is encodable by any Encoder.
+func negotiate(accept string, body interface{}) (string, Encoder, bool) {
+	ranges := parseAccept(accept)
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	var (
+		best  mediaEncoder
+		bestQ float64
+		ok    bool
+	)
+
+	for _, me := range encoders {
+		q := ranges.quality(me.mediaType)
+		if q <= bestQ {
+			continue
+		}
+		if e, isModel := me.encoder.(ModelEncoder); isModel && body != nil && !e.CanEncode(body) {
+			continue
+		}
+		best, bestQ, ok = me, q, true
+	}
+
+	return best.mediaType, best.encoder, ok
+}
+
+// acceptRange is a media range of an Accept header, i.e. text/*;q=0.5
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+type acceptRanges []acceptRange
+
+// parseAccept parses an Accept header into media ranges,
+// the most specific first.
+func parseAccept(accept string) acceptRanges {
+	if strings.TrimSpace(accept) == "" {
+		return acceptRanges{{mediaType: "*/*", q: 1}}
+	}
+
+	ranges := acceptRanges{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		r := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if r.mediaType == "" {
+			continue
+		}
+		if r.mediaType == "*" {
+			r.mediaType = "*/*"
+		}
+
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(strings.TrimSpace(kv[0])) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+
+	return ranges
+}
+
+// quality returns the quality of a media type according to
+// the most specific range matching it, 0 if none does.
+func (ranges acceptRanges) quality(mediaType string) float64 {
+	for _, r := range ranges {
+		if matchMediaType(r.mediaType, mediaType) {
+			return r.q
+		}
+	}
+	return 0
+}
+
+func matchMediaType(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, pattern[:len(pattern)-1])
+	}
+
+	return false
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// notAcceptable is the Error returned when no Encoder is acceptable.
+func notAcceptable(accept string) *Error {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	types := make([]string, len(encoders))
+	for i, me := range encoders {
+		types[i] = me.mediaType
+	}
+
+	return &Error{
+		Status: strconv.Itoa(http.StatusNotAcceptable),
+		Title:  http.StatusText(http.StatusNotAcceptable),
+		Detail: "cannot respond with " + accept + ", available media types are " + strings.Join(types, ", "),
+	}
+}
+
+func init() {
+	RegisterEncoder("application/json", JsonEncoder{})
+	RegisterEncoder("application/xml", XmlEncoder{})
+	RegisterEncoder("text/xml", XmlEncoder{})
+	RegisterEncoder("application/msgpack", MsgpackEncoder{})
+	RegisterEncoder("application/x-msgpack", MsgpackEncoder{})
+	RegisterEncoder("text/csv", CsvEncoder{})
+	RegisterEncoder("text/plain", TextEncoder{})
+	RegisterEncoder(JSONAPIMediaType, JsonapiEncoder{})
+}
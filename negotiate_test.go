@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type marshaller struct {
+	body    interface{}
+	status  int
+	headers map[string]string
+}
+
+func (m marshaller) Body(ctx context.Context) interface{}          { return m.body }
+func (m marshaller) Headers(ctx context.Context) map[string]string { return m.headers }
+func (m marshaller) Status(ctx context.Context) int                { return m.status }
+
+func sendWithAccept(accept string, rm ResponseMarshaller) (*httptest.ResponseRecorder, error) {
+	res := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/pets", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	err := Send(context.Background(), WrapReq(res, r), rm)
+	return res, err
+}
+
+var _ = Describe("Content negotiation", func() {
+	It("picks the preferred media type", func() {
+		tests := []struct {
+			accept   string
+			expected string
+		}{
+			{"", "application/json"},
+			{"*/*", "application/json"},
+			{"application/xml", "application/xml"},
+			{"text/*", "text/xml"},
+			{"text/*;q=0.5, text/csv", "text/csv"},
+			{"application/json;q=0.1, application/msgpack;q=0.9", "application/msgpack"},
+			{"text/plain, */*;q=0", "text/plain"},
+			{"application/xml, application/json", "application/json"},
+		}
+
+		for _, tt := range tests {
+			mediaType, _, ok := Negotiate(tt.accept)
+			Expect(ok).To(BeTrue(), tt.accept)
+			Expect(mediaType).To(Equal(tt.expected), tt.accept)
+		}
+
+		_, _, ok := Negotiate("image/png, */*;q=0")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("sends the body with the negotiated encoder", func() {
+		res, err := sendWithAccept("application/xml", marshaller{body: pet{ID: "1", Name: "simba"}, status: 200})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/xml"))
+		Expect(res.Body.String()).To(ContainSubstring("<Name>simba</Name>"))
+	})
+
+	It("sends responses without body", func() {
+		res, err := sendWithAccept("image/png", marshaller{status: http.StatusNoContent})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Code).To(Equal(http.StatusNoContent))
+		Expect(res.Body.Len()).To(Equal(0))
+
+		res, err = sendWithAccept("", marshaller{status: http.StatusOK})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Code).To(Equal(http.StatusOK))
+		Expect(res.Header().Get("Content-Type")).To(BeEmpty())
+		Expect(res.Body.Len()).To(Equal(0))
+	})
+
+	It("returns a 406 error when nothing is acceptable", func() {
+		res, err := sendWithAccept("image/png", marshaller{body: pet{ID: "1"}, status: 200})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusNotAcceptable))
+
+		r, _ := http.NewRequest("GET", "/pets", nil)
+		HandleError(WrapReq(res, r), err)
+		Expect(res.Code).To(Equal(http.StatusNotAcceptable))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json"))
+	})
+
+	It("does not encode untagged models as jsonapi.org documents", func() {
+		res, err := sendWithAccept(JSONAPIMediaType, marshaller{body: pet{ID: "1"}, status: 200})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusNotAcceptable))
+
+		p := &Paginated{Data: []pet{{ID: "1"}}, Page: Page{Limit: 10}}
+		res, err = sendWithAccept(JSONAPIMediaType+", application/json;q=0.5", marshaller{body: p, status: 200})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(res.Body.String()).To(MatchJSON(`[{"id":"1"}]`))
+
+		_, encoder, _ := Negotiate(JSONAPIMediaType)
+		Expect(encoder.(ModelEncoder).CanEncode([]*crudPet{})).To(BeTrue())
+	})
+
+	It("sends no pagination headers with a 406 error", func() {
+		total := 45
+		p := &Paginated{Data: []pet{{ID: "1"}}, Page: Page{Offset: 20, Limit: 10}, Total: &total}
+
+		res, err := sendWithAccept("image/png", marshaller{body: p, status: 200})
+		Expect(err.(*Error).HTTPStatus()).To(Equal(http.StatusNotAcceptable))
+		Expect(res.Header().Get("Link")).To(BeEmpty())
+		Expect(res.Header().Get("X-Total-Count")).To(BeEmpty())
+	})
+
+	It("handles stacks of errors", func() {
+		res := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/pets", nil)
+		HandleError(WrapReq(res, r), NewError(400, "a").Add(NewError(422, "b")))
+		Expect(res.Code).To(Equal(422))
+		Expect(res.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"a"},{"status":"422","title":"b"}]}`))
+	})
+})
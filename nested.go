@@ -0,0 +1,280 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Parent is the parent model of a nested resource, see Under.
+type Parent struct {
+	Name  string      // Name of the parent resource, i.e. users
+	Field string      // JSON name of the field of the child models holding the parent id, i.e. user_id
+	ID    string      // Id of the parent model, from the :id path parameter
+	Model interface{} // The parent model, found by the parent DataSource
+}
+
+type parentKey struct{}
+
+// WithParent returns a context holding the parent model of a nested resource.
+func WithParent(ctx context.Context, p Parent) context.Context {
+	return context.WithValue(ctx, parentKey{}, p)
+}
+
+// ParentFrom returns the parent model held by a context, if any.
+func ParentFrom(ctx context.Context) (Parent, bool) {
+	p, ok := ctx.Value(parentKey{}).(Parent)
+	return p, ok
+}
+
+// Under nests a resource under the models of a parent DataSource,
+// i.e. AddResource("pets", pets, Under("users", users, "user_id")) serves
+//
+//	/users/:id/pets
+//	/users/:id/pets/:item_id
+//	/users/:id/relationships/pets
+//
+// The parent id takes the :id segment of the routes of the parent resource,
+// so that routers such as httprouter serve both.
+// Before parsing requests, the parent id is moved to the :user_id
+// path parameter, and the id of the nested model to :id.
+//
+// Requests for a parent which does not exist are a 404.
+// Otherwise the parent is put on the context with WithParent,
+// the Query gets a filter on field equal to the parent id,
+// and the models to create or update get their field set to it.
+// Models of other parents are not found.
+func Under(parent string, src DataSource, field string) ResourceOption {
+	return func(c *resourceConfig) {
+		c.parent = &parentConfig{name: strings.Trim(parent, "/"), source: src, field: field}
+	}
+}
+
+// nestedIDParam is the path parameter of the ids of nested models.
+const nestedIDParam = "item_id"
+
+type parentConfig struct {
+	name   string
+	source DataSource
+	field  string
+}
+
+// nestedParser scopes the requests of a nested resource to its parent.
+type nestedParser struct {
+	RequestParser
+	action Action
+	name   string     // Name of the nested resource
+	source DataSource // DataSource of the nested resource
+	parent *parentConfig
+
+	// Soft delete settings of the nested resource, see SoftDelete.
+	softDelete bool
+	authorize  func(context.Context, *Req) error
+}
+
+func (p nestedParser) ParseRequest(ctx context.Context, r *Req) (context.Context, error) {
+	var id string
+	if r.Params != nil {
+		id = p.moveParams(r.Params)
+	}
+
+	c, err := p.parent.source.FindOne(WithQuery(ctx, Query{ID: id}))
+	if err != nil {
+		return ctx, err
+	}
+	ctx = WithParent(ctx, Parent{
+		Name:  p.parent.name,
+		Field: p.parent.field,
+		ID:    id,
+		Model: ResultFrom(c).Data,
+	})
+
+	ctx, err = p.RequestParser.ParseRequest(ctx, r)
+	if err != nil {
+		return ctx, err
+	}
+
+	q, ok := ctx.Value(queryKey).(Query)
+	if !ok {
+		if q, err = ParseQuery(r); err != nil {
+			return ctx, err
+		}
+	}
+	q.Filters = append(q.Filters, Filter{Field: p.parent.field, Op: OpEq, Values: []string{id}})
+	ctx = WithQuery(ctx, q)
+
+	models := ModelsFrom(ctx)
+	if model := ModelFrom(ctx); model != nil {
+		models = append(models, model)
+	}
+	for _, model := range models {
+		if err := setField(model, p.parent.field, id); err != nil {
+			return ctx, WrapErr(err, http.StatusInternalServerError)
+		}
+	}
+
+	// Models of other parents are not found.
+	ids := append([]string{}, q.IDs...)
+	if q.ID != "" {
+		ids = append(ids, q.ID)
+	}
+	if p.action == ActionBulkUpdate {
+		for _, model := range models {
+			ids = append(ids, ModelID(model))
+		}
+	}
+	// Soft deleted models are only looked up to restore them,
+	// or when include_deleted=true is authorized.
+	includeDeleted := p.action == ActionRestore
+	if q.IncludeDeleted && !includeDeleted {
+		if err := p.authorizeDeleted(ctx, r); err != nil {
+			return ctx, err
+		}
+		includeDeleted = true
+	}
+	for _, child := range ids {
+		if err := p.owned(ctx, child, id, includeDeleted); err != nil {
+			return ctx, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// moveParams moves the parent id of the :id path parameter to the :field one,
+// and the id of the nested model to :id. It returns the parent id.
+func (p nestedParser) moveParams(params *Params) string {
+	field := ":" + p.parent.field
+	if id, ok := params.Path[field]; ok {
+		return strings.Join(id, ",")
+	}
+
+	path := Values{}
+	for key, values := range params.Path {
+		path[key] = values
+	}
+	path[field] = path[":id"]
+	delete(path, ":id")
+	if item, ok := path[":"+nestedIDParam]; ok {
+		path[":id"] = item
+		delete(path, ":"+nestedIDParam)
+	}
+
+	params.Path = path
+	if params.Values != nil {
+		params.Values = params.calcValues()
+	}
+	return strings.Join(path[field], ",")
+}
+
+// authorizeDeleted checks that the query of the context may include
+// soft deleted models, as the Resource does, see Resource.authorizeDeleted.
+func (p nestedParser) authorizeDeleted(ctx context.Context, r *Req) error {
+	res := &Resource{Req: r, SoftDelete: p.softDelete, AuthorizeDeleted: p.authorize}
+	return res.authorizeDeleted(ctx)
+}
+
+// owned checks that the model of an id belongs to the parent.
+func (p nestedParser) owned(ctx context.Context, id, parentID string, includeDeleted bool) error {
+	if id == "" {
+		return nil
+	}
+
+	q := QueryFrom(ctx)
+	q.ID = id
+	q.IncludeDeleted = includeDeleted
+
+	c, err := p.source.FindOne(WithQuery(ctx, q))
+	if err != nil {
+		return err
+	}
+
+	if fieldString(ResultFrom(c).Data, p.parent.field) != parentID {
+		err := NewError(http.StatusNotFound, "Not found")
+		err.Code = "not_found"
+		err.Detail = fmt.Sprintf("%s %s does not exist", p.name, id)
+		return err
+	}
+	return nil
+}
+
+// relationshipDocument returns the resource identifiers of the models
+// of a nested resource, with links to the relationship and the models.
+func relationshipDocument(data interface{}, typ, related string) *Document {
+	ids := []*ResourceIdentifier{}
+
+	v := indirect(reflect.ValueOf(data))
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			ids = append(ids, &ResourceIdentifier{Type: typ, ID: ModelID(v.Index(i).Interface())})
+		}
+	}
+
+	return &Document{
+		Data: ids,
+		Links: map[string]string{
+			"self":    path.Dir(related) + "/relationships/" + path.Base(related),
+			"related": related,
+		},
+	}
+}
+
+// fieldString returns the field of a struct or map model from its JSON name.
+func fieldString(model interface{}, name string) string {
+	v := indirect(reflect.ValueOf(model))
+
+	var f reflect.Value
+	switch v.Kind() {
+	case reflect.Struct:
+		if idx := jsonFieldIndex(v.Type(), name); idx != nil {
+			f = indirect(v.FieldByIndex(idx))
+		}
+	case reflect.Map:
+		f = indirect(v.MapIndex(reflect.ValueOf(name)))
+	}
+
+	if !f.IsValid() {
+		return ""
+	}
+	return valueString(f)
+}
+
+// setField sets the field of a struct or map model from its JSON name.
+func setField(model interface{}, name, value string) error {
+	v := indirect(reflect.ValueOf(model))
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if idx := jsonFieldIndex(v.Type(), name); idx != nil && v.CanAddr() {
+			return setValue(v.FieldByIndex(idx), value)
+		}
+	case reflect.Map:
+		v.SetMapIndex(reflect.ValueOf(name), reflect.ValueOf(value))
+		return nil
+	}
+	return fmt.Errorf("api: cannot set the %s field of a %T", name, model)
+}
+
+// jsonFieldIndex returns the index of the field of a struct type
+// from its JSON name, or nil.
+func jsonFieldIndex(t reflect.Type, name string) []int {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		n, _ := parseJSONTag(f.Tag.Get("json"))
+		if n == "" {
+			n = f.Name
+		}
+		if n == name {
+			return f.Index
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type nestedUser struct {
+	ID string `json:"id"`
+}
+
+type nestedPet struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name" validate:"required"`
+	UserID string `json:"user_id"`
+}
+
+// trashLookups records whether each FindOne includes soft deleted models.
+type trashLookups struct {
+	*MemorySource
+	includeDeleted []bool
+}
+
+func (s *trashLookups) FindOne(ctx context.Context) (context.Context, error) {
+	s.includeDeleted = append(s.includeDeleted, QueryFrom(ctx).IncludeDeleted)
+	return s.MemorySource.FindOne(ctx)
+}
+
+var _ = Describe("Under", func() {
+	var api *API
+	var users *MemorySource
+
+	BeforeEach(func() {
+		users = NewMemorySource(nestedUser{})
+		users.Create(WithModel(context.Background(), &nestedUser{}))
+		users.Create(WithModel(context.Background(), &nestedUser{}))
+
+		api = New("/v1")
+		api.AddResource("pets", NewMemorySource(nestedPet{}), ResourceModel(nestedPet{}), Under("users", users, "user_id"), Enable(ActionBulkDelete))
+
+		Expect(serve(api, "POST", "/v1/users/1/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusCreated))
+		Expect(serve(api, "POST", "/v1/users/2/pets", `{"name":"nala","user_id":"1"}`).Code).To(Equal(http.StatusCreated))
+	})
+
+	It("scopes the resource to its parent", func() {
+		w := serve(api, "POST", "/v1/users/1/pets", `{"name":"kiara"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/v1/users/1/pets/3"))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"3","name":"kiara","user_id":"1"}`))
+
+		w = serve(api, "GET", "/v1/users/1/pets", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`[
+			{"id":"1","name":"simba","user_id":"1"},
+			{"id":"3","name":"kiara","user_id":"1"}
+		]`))
+
+		w = serve(api, "GET", "/v1/users/2/pets", "")
+		Expect(w.Body.String()).To(MatchJSON(`[{"id":"2","name":"nala","user_id":"2"}]`))
+	})
+
+	It("escapes the parent id of Location headers", func() {
+		users := NewMemorySource(nestedUser{})
+		users.Create(WithModel(context.Background(), &nestedUser{ID: "a b"}))
+		api.AddResource("pets", NewMemorySource(nestedPet{}), ResourceModel(nestedPet{}), Under("owners", users, "user_id"))
+
+		w := serve(api, "POST", "/v1/owners/a%20b/pets", `{"name":"kiara"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/v1/owners/a%20b/pets/1"))
+	})
+
+	It("does not find models of other parents", func() {
+		Expect(serve(api, "GET", "/v1/users/2/pets/2", "").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "GET", "/v1/users/1/pets/2", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "PATCH", "/v1/users/1/pets/2", `{"name":"kovu"}`).Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "DELETE", "/v1/users/1/pets/2", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "DELETE", "/v1/users/1/pets?id=1,2", "").Code).To(Equal(http.StatusNotFound))
+
+		w := serve(api, "PATCH", "/v1/users/2/pets/2", `{"name":"kovu","user_id":"1"}`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"2","name":"kovu","user_id":"2"}`))
+	})
+
+	It("does not patch models to other parents", func() {
+		for mediaType, body := range map[string]string{
+			MergePatchMediaType: `{"name":"kovu","user_id":"2"}`,
+			JSONPatchMediaType:  `[{"op":"replace","path":"/user_id","value":"2"}]`,
+		} {
+			w := serve(api, "PATCH", "/v1/users/1/pets/1", body, "Content-Type", mediaType)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"user_id":"1"`))
+		}
+
+		Expect(serve(api, "GET", "/v1/users/1/pets/1", "").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "GET", "/v1/users/2/pets/1", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("requires the parent to exist", func() {
+		w := serve(api, "GET", "/v1/users/9/pets", "")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Body.String()).To(ContainSubstring("nestedUser 9 does not exist"))
+
+		Expect(serve(api, "POST", "/v1/users/9/pets", `{"name":"kiara"}`).Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("serves relationships", func() {
+		w := serve(api, "GET", "/v1/users/1/relationships/pets", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{
+			"data": [{"type":"pets","id":"1"}],
+			"links": {
+				"self": "/v1/users/1/relationships/pets",
+				"related": "/v1/users/1/pets"
+			}
+		}`))
+
+		Expect(serve(api, "GET", "/v1/users/9/relationships/pets", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("activates next to its parent on httprouter", func() {
+		api := New("/v1")
+		api.AddResource("users", users, ResourceModel(nestedUser{}))
+		api.AddResource("pets", NewMemorySource(nestedPet{}), ResourceModel(nestedPet{}), Under("users", users, "user_id"), Enable(ActionRestore))
+
+		router := httprouter.New()
+		Expect(func() { api.Activate(router) }).ToNot(Panic())
+
+		w := serve(router, "POST", "/v1/users/2/pets", `{"name":"nala"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/v1/users/2/pets/1"))
+
+		w = serve(router, "GET", "/v1/users/2", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"2"}`))
+
+		w = serve(router, "GET", "/v1/users/2/pets/1", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"nala","user_id":"2"}`))
+
+		Expect(serve(router, "GET", "/v1/users/1/pets/1", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(router, "GET", "/v1/users/2/relationships/pets", "").Code).To(Equal(http.StatusOK))
+	})
+
+	It("only looks up soft deleted models when authorized or restoring", func() {
+		pets := &trashLookups{MemorySource: NewMemorySource(nestedPet{})}
+		api := New("/v1")
+		api.AddResource("pets", pets, ResourceModel(nestedPet{}), Under("users", users, "user_id"), SoftDelete(nil))
+
+		Expect(serve(api, "POST", "/v1/users/1/pets", `{"name":"simba"}`).Code).To(Equal(http.StatusCreated))
+		Expect(serve(api, "DELETE", "/v1/users/1/pets/1", "").Code).To(Equal(http.StatusNoContent))
+
+		pets.includeDeleted = nil
+		w := serve(api, "GET", "/v1/users/1/pets/1?include_deleted=true", "")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(pets.includeDeleted).ToNot(ContainElement(true))
+
+		pets.includeDeleted = nil
+		Expect(serve(api, "POST", "/v1/users/1/pets/1/restore", "").Code).To(Equal(http.StatusOK))
+		Expect(pets.includeDeleted).To(ContainElement(true))
+
+		api.AddResource("pets", pets, ResourceModel(nestedPet{}), Under("owners", users, "user_id"), SoftDelete(func(context.Context, *Req) error { return nil }))
+		Expect(serve(api, "DELETE", "/v1/owners/1/pets/1", "").Code).To(Equal(http.StatusNoContent))
+		Expect(serve(api, "GET", "/v1/owners/1/pets/1", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "GET", "/v1/owners/1/pets/1?include_deleted=true", "").Code).To(Equal(http.StatusOK))
+	})
+})
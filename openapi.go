@@ -0,0 +1,327 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification
+// documents are generated for.
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPI is an OpenAPI document describing the endpoints of an API.
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// OpenAPIInfo holds the metadata of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the schemas referenced by an OpenAPI document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation describes an endpoint in an OpenAPI document.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path, query or header parameter of an Operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes a response of an Operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body for a content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPI generates an OpenAPI document from the endpoints of the API.
+func (api *API) OpenAPI(info OpenAPIInfo) *OpenAPI {
+	g := newSchemaGenerator()
+
+	doc := &OpenAPI{
+		OpenAPI: OpenAPIVersion,
+		Info:    info,
+		Paths:   map[string]map[string]*Operation{},
+	}
+
+	errors := g.schema(reflect.TypeOf(Errors{}))
+	ids := map[string]bool{}
+
+	for _, e := range api.Endpoints {
+		if e.Hidden {
+			continue
+		}
+
+		// Endpoints appended without API.Add are not normalized yet.
+		e.describe()
+
+		path := openAPIPath(api.Prefix + e.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+
+		op := &Operation{
+			OperationID: uniqueID(ids, operationID(e.Method, api.Prefix+e.Path)),
+			Summary:     e.Summary,
+			Description: e.Description,
+			Tags:        e.Tags,
+			Deprecated:  e.Deprecated,
+			Responses:   map[string]*Response{},
+		}
+
+		for _, p := range e.Params {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:        p.Name,
+				In:          p.In,
+				Description: p.Description,
+				Required:    p.Required,
+				Schema:      p.schema(g),
+			})
+		}
+
+		if t := e.RequestType(); t != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(g.schema(t)),
+			}
+
+			if len(e.Consumes) > 0 {
+				op.RequestBody.Content = map[string]*MediaType{}
+				for _, mediaType := range e.Consumes {
+					op.RequestBody.Content[mediaType] = &MediaType{Schema: g.schema(t)}
+				}
+			}
+		}
+
+		for status, t := range e.ResponseTypes() {
+			res := &Response{Description: http.StatusText(status)}
+			if t != nil {
+				res.Content = jsonContent(g.schema(t))
+			}
+			op.Responses[strconv.Itoa(status)] = res
+		}
+
+		if len(op.Responses) == 0 {
+			op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+		}
+
+		op.Responses["default"] = &Response{
+			Description: "Error",
+			Content:     jsonContent(errors),
+		}
+
+		doc.Paths[path][strings.ToLower(e.Method)] = op
+	}
+
+	doc.Components.Schemas = g.components
+	return doc
+}
+
+// JSON returns the document encoded as JSON.
+func (doc *OpenAPI) JSON() ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// YAML returns the document encoded as YAML,
+// with the keys in the order of the JSON document.
+func (doc *OpenAPI) YAML() ([]byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := yamlValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// yamlValue reads the next JSON value of dec,
+// decoding objects into MapSlices to keep the order of their keys.
+func yamlValue(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := t.(type) {
+	case json.Delim:
+		if t == '{' {
+			m := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := yamlValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, yaml.MapItem{Key: key, Value: value})
+			}
+			_, err := dec.Token()
+			return m, err
+		}
+
+		s := []interface{}{}
+		for dec.More() {
+			value, err := yamlValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+		}
+		_, err := dec.Token()
+		return s, err
+
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	}
+
+	return t, nil
+}
+
+// ServeOpenAPI adds an endpoint serving the OpenAPI document of the API.
+// The document is served as YAML when the path ends with .yaml or .yml,
+// and as JSON otherwise.
+func (api *API) ServeOpenAPI(path string, info OpenAPIInfo) {
+	asYAML := strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+
+	api.Add(Endpoint{
+		Method: "GET",
+		Path:   path,
+		Hidden: true,
+		Implementation: func(ctx context.Context, r *Req) {
+			var (
+				data        []byte
+				err         error
+				contentType = "application/json"
+			)
+
+			doc := api.OpenAPI(info)
+			if asYAML {
+				contentType = "application/yaml"
+				data, err = doc.YAML()
+			} else {
+				data, err = doc.JSON()
+			}
+
+			if err != nil {
+				HandleError(r, err)
+				return
+			}
+
+			r.Response.Header().Set("Content-Type", contentType)
+			r.Response.WriteHeader(http.StatusOK)
+			r.Response.Write(data)
+		},
+	})
+}
+
+// schema describes the type and constraints of a parameter.
+func (p Param) schema(g *schemaGenerator) *Schema {
+	s := g.schema(p.GoType())
+
+	values := s
+	if s.Type == "array" {
+		values = s.Items
+	}
+
+	if p.Format != "" {
+		values.Format = p.Format
+	}
+	values.Enum = p.Enum
+	values.Pattern = p.Pattern
+	values.Minimum = p.Min
+	values.Maximum = p.Max
+	values.MinLength = p.MinLength
+	values.MaxLength = p.MaxLength
+
+	return s
+}
+
+// openAPIPath converts an endpoint path to an OpenAPI path,
+// i.e. /users/:id becomes /users/{id}
+func openAPIPath(path string) string {
+	return RewritePath(path, func(name string) string {
+		return "{" + name + "}"
+	}, func(name string) string {
+		return "{" + name + "}"
+	})
+}
+
+// operationID derives an operation id from a method and a path,
+// i.e. GET /users/:id becomes getUsersId
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, s := range splitPath(path) {
+		s = strings.TrimLeft(s, ":*")
+		for _, w := range strings.FieldsFunc(s, func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		}) {
+			id += strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return id
+}
+
+// uniqueID adds a numeric suffix to an id already used,
+// i.e. for /users-x and /users_x, and marks it as used.
+func uniqueID(used map[string]bool, id string) string {
+	unique := id
+	for n := 2; used[unique]; n++ {
+		unique = id + strconv.Itoa(n)
+	}
+	used[unique] = true
+	return unique
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type owner struct {
+	Name  string    `json:"name"`
+	Since time.Time `json:"since,omitempty"`
+	Pets  []pet     `json:"pets,omitempty"`
+	Token string    `json:"token" out:"false"`
+}
+
+func makeDocumentedAPI() *API {
+	api := New("/v1")
+
+	api.Add(Endpoint{
+		Method:      "GET",
+		Path:        "/owners/:id",
+		Summary:     "Read an owner",
+		Description: "Returns an owner with its pets",
+		Tags:        []string{"owners"},
+		Responses:   map[int]interface{}{200: owner{}},
+		Implementation: func(ctx context.Context, r *Req) {
+			r.NoContent(http.StatusOK)
+		},
+	})
+
+	api.Add(Endpoint{
+		Method:    "POST",
+		Path:      "/owners",
+		Request:   owner{},
+		Responses: map[int]interface{}{201: &owner{}},
+		Implementation: func(ctx context.Context, r *Req) {
+			r.NoContent(http.StatusCreated)
+		},
+	})
+
+	return api
+}
+
+var _ = Describe("OpenAPI", func() {
+	It("describes the endpoints", func() {
+		doc := makeDocumentedAPI().OpenAPI(OpenAPIInfo{Title: "pets", Version: "1.0.0"})
+
+		Expect(doc.OpenAPI).To(Equal("3.1.0"))
+		Expect(doc.Paths).To(HaveKey("/v1/owners/{id}"))
+		Expect(doc.Paths).To(HaveKey("/v1/owners"))
+
+		op := doc.Paths["/v1/owners/{id}"]["get"]
+		Expect(op.OperationID).To(Equal("getV1OwnersId"))
+		Expect(op.Summary).To(Equal("Read an owner"))
+		Expect(op.Tags).To(Equal([]string{"owners"}))
+		Expect(op.Parameters).To(HaveLen(1))
+		Expect(op.Parameters[0].Name).To(Equal("id"))
+		Expect(op.Parameters[0].In).To(Equal("path"))
+		Expect(op.Responses["200"].Content["application/json"].Schema.Ref).To(Equal("#/components/schemas/owner"))
+		Expect(op.Responses["default"].Content["application/json"].Schema.Ref).To(Equal("#/components/schemas/Errors"))
+
+		create := doc.Paths["/v1/owners"]["post"]
+		Expect(create.RequestBody.Content["application/json"].Schema.Ref).To(Equal("#/components/schemas/owner"))
+		Expect(create.Responses).To(HaveKey("201"))
+	})
+
+	It("gives a unique id to each operation", func() {
+		api := New("/v1")
+		for _, path := range []string{"/users-x", "/users_x", "/users/x"} {
+			api.Add(Endpoint{
+				Method: "GET",
+				Path:   path,
+				Implementation: func(ctx context.Context, r *Req) {
+					r.NoContent(http.StatusOK)
+				},
+			})
+		}
+
+		doc := api.OpenAPI(OpenAPIInfo{Title: "users", Version: "1.0.0"})
+		Expect(doc.Paths["/v1/users-x"]["get"].OperationID).To(Equal("getV1UsersX"))
+		Expect(doc.Paths["/v1/users_x"]["get"].OperationID).To(Equal("getV1UsersX2"))
+		Expect(doc.Paths["/v1/users/x"]["get"].OperationID).To(Equal("getV1UsersX3"))
+	})
+
+	It("describes the types as components", func() {
+		doc := makeDocumentedAPI().OpenAPI(OpenAPIInfo{Title: "pets", Version: "1.0.0"})
+
+		o := doc.Components.Schemas["owner"]
+		Expect(o.Type).To(Equal("object"))
+		Expect(o.Properties).ToNot(HaveKey("token"))
+		Expect(o.Properties["since"].Format).To(Equal("date-time"))
+		Expect(o.Properties["pets"].Items.Ref).To(Equal("#/components/schemas/pet"))
+		Expect(o.Required).To(Equal([]string{"name"}))
+
+		errs := doc.Components.Schemas["Errors"]
+		Expect(errs.Properties["errors"].Items.Ref).To(Equal("#/components/schemas/Error"))
+		Expect(doc.Components.Schemas["Error"].Properties).To(HaveKey("title"))
+	})
+
+	It("serves the document as JSON", func() {
+		api := makeDocumentedAPI()
+		api.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "pets", Version: "1.0.0"})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/openapi.json", nil)
+		api.ServeHTTP(res, req)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		doc := map[string]interface{}{}
+		Expect(json.Unmarshal(res.Body.Bytes(), &doc)).To(Succeed())
+		Expect(doc["openapi"]).To(Equal("3.1.0"))
+		Expect(doc["paths"]).ToNot(HaveKey("/v1/openapi.json"))
+	})
+
+	It("serves the document as YAML", func() {
+		api := makeDocumentedAPI()
+		api.ServeOpenAPI("/openapi.yaml", OpenAPIInfo{Title: "pets", Version: "1.0.0"})
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/openapi.yaml", nil)
+		api.ServeHTTP(res, req)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/yaml"))
+		Expect(strings.HasPrefix(res.Body.String(), "openapi: 3.1.0\n")).To(BeTrue())
+	})
+
+	It("keeps the order of the keys of the JSON document in YAML", func() {
+		b, err := makeDocumentedAPI().OpenAPI(OpenAPIInfo{Title: "pets", Version: "1.0.0"}).YAML()
+		Expect(err).ToNot(HaveOccurred())
+
+		y := string(b)
+		Expect(strings.Index(y, "summary: Read an owner")).To(BeNumerically("<", strings.Index(y, "description: Returns an owner")))
+	})
+})
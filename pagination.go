@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Paginated is a page of models, to be sent as the body of a response.
+// Send encodes its Data, with RFC 8288 Link headers to the first,
+// previous, next and last pages and an X-Total-Count header.
+// JSON:API documents also get these as links and meta.total.
+type Paginated struct {
+	Data  interface{} // A slice of models
+	Page  Page        // The page of the query
+	Total *int        // Number of models of all pages, or nil when unknown
+
+	// Cursors of the next and previous pages, for cursor pagination.
+	// Empty when there is no such page.
+	NextCursor string
+	PrevCursor string
+}
+
+// Links returns the URLs of the first, prev, next and last pages,
+// by relation. Cursors are encoded with EncodeCursor.
+// The last page is only known for offset pages with a Total.
+func (p *Paginated) Links(u *url.URL) map[string]string {
+	links := map[string]string{}
+
+	if p.Page.Cursor != "" || p.NextCursor != "" || p.PrevCursor != "" {
+		links["first"] = pageURL(u, "", "")
+		if p.PrevCursor != "" {
+			links["prev"] = pageURL(u, "page[cursor]", EncodeCursor(p.PrevCursor))
+		}
+		if p.NextCursor != "" {
+			links["next"] = pageURL(u, "page[cursor]", EncodeCursor(p.NextCursor))
+		}
+		return links
+	}
+
+	limit, offset := p.Page.Limit, p.Page.Offset
+	if limit <= 0 {
+		return links
+	}
+
+	links["first"] = pageURL(u, "page[offset]", "0")
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links["prev"] = pageURL(u, "page[offset]", strconv.Itoa(prev))
+	}
+
+	if p.Total != nil {
+		if offset+limit < *p.Total {
+			links["next"] = pageURL(u, "page[offset]", strconv.Itoa(offset+limit))
+		}
+		last := 0
+		if *p.Total > 0 {
+			last = (*p.Total - 1) / limit * limit
+		}
+		links["last"] = pageURL(u, "page[offset]", strconv.Itoa(last))
+	} else if length(p.Data) >= limit {
+		// Without a total, a full page may be followed by another one.
+		links["next"] = pageURL(u, "page[offset]", strconv.Itoa(offset+limit))
+	}
+
+	return links
+}
+
+// LinkHeader formats links as an RFC 8288 Link header.
+func LinkHeader(links map[string]string) string {
+	var parts []string
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if link, ok := links[rel]; ok {
+			parts = append(parts, "<"+link+`>; rel="`+rel+`"`)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// headers returns the Link and X-Total-Count headers of a page.
+func (p *Paginated) headers(u *url.URL) map[string]string {
+	headers := map[string]string{}
+	if link := LinkHeader(p.Links(u)); link != "" {
+		headers["Link"] = link
+	}
+	if p.Total != nil {
+		headers["X-Total-Count"] = strconv.Itoa(*p.Total)
+	}
+	return headers
+}
+
+// pageURL returns the URL of a request with other page parameters.
+func pageURL(u *url.URL, key, value string) string {
+	query := u.Query()
+	query.Del("page[offset]")
+	query.Del("page[cursor]")
+	if key != "" {
+		query.Set(key, value)
+	}
+
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+// length returns the length of a slice, or 0.
+func length(v interface{}) int {
+	rv := indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		return rv.Len()
+	}
+	return 0
+}
+
+var (
+	cursorMu  sync.RWMutex
+	cursorKey = randomKey()
+)
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("api: cannot generate a cursor key: " + err.Error())
+	}
+	return key
+}
+
+// SetCursorKey sets the secret key signing cursors.
+// By default, a random key is generated at startup, so cursors
+// are not understood by other instances of an API, or after a restart.
+func SetCursorKey(key []byte) {
+	cursorMu.Lock()
+	defer cursorMu.Unlock()
+	cursorKey = append([]byte(nil), key...)
+}
+
+// EncodeCursor encodes a cursor of a DataSource into an opaque token,
+// signed so that clients cannot tamper with it.
+func EncodeCursor(cursor string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(cursor))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signCursor(payload))
+}
+
+// DecodeCursor decodes a token made by EncodeCursor.
+func DecodeCursor(token string) (string, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", errInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(sig, signCursor(token[:i])) {
+		return "", errInvalidCursor
+	}
+
+	cursor, err := base64.RawURLEncoding.DecodeString(token[:i])
+	if err != nil {
+		return "", errInvalidCursor
+	}
+	return string(cursor), nil
+}
+
+var errInvalidCursor = errors.New("api: invalid cursor")
+
+func signCursor(payload string) []byte {
+	cursorMu.RLock()
+	defer cursorMu.RUnlock()
+
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+// pagedPetSource pages its pets with offsets, or with ids as cursors.
+type pagedPetSource struct {
+	crudPetSource
+}
+
+func (s *pagedPetSource) FindAll(ctx context.Context) (context.Context, error) {
+	ctx, _ = s.crudPetSource.FindAll(ctx)
+	pets := ResultFrom(ctx).Data.([]*crudPet)
+	total := len(pets)
+	page := QueryFrom(ctx).Page
+
+	start := page.Offset
+	if page.Cursor != "" {
+		for i, p := range pets {
+			if p.ID == page.Cursor {
+				start = i + 1
+			}
+		}
+	}
+
+	end := total
+	if page.Limit > 0 && start+page.Limit < total {
+		end = start + page.Limit
+	}
+
+	result := Result{Data: pets[start:end], Total: &total}
+	if page.Cursor != "" && end < total {
+		result.NextCursor = pets[end-1].ID
+	}
+	return WithResult(ctx, result), nil
+}
+
+var _ = Describe("Pagination", func() {
+	u, _ := url.Parse("/v1/pets?sort=name&page[offset]=20&page[limit]=10")
+	total := func(n int) *int { return &n }
+
+	It("links offset pages", func() {
+		p := &Paginated{Page: Page{Offset: 20, Limit: 10}, Total: total(45)}
+
+		Expect(p.Links(u)).To(Equal(map[string]string{
+			"first": "/v1/pets?page%5Blimit%5D=10&page%5Boffset%5D=0&sort=name",
+			"prev":  "/v1/pets?page%5Blimit%5D=10&page%5Boffset%5D=10&sort=name",
+			"next":  "/v1/pets?page%5Blimit%5D=10&page%5Boffset%5D=30&sort=name",
+			"last":  "/v1/pets?page%5Blimit%5D=10&page%5Boffset%5D=40&sort=name",
+		}))
+
+		Expect(LinkHeader(map[string]string{"next": "/b", "first": "/a"})).To(Equal(`</a>; rel="first", </b>; rel="next"`))
+	})
+
+	It("links offset pages without a total", func() {
+		p := &Paginated{Data: make([]pet, 10), Page: Page{Offset: 20, Limit: 10}}
+		Expect(p.Links(u)).To(HaveKey("next"))
+		Expect(p.Links(u)).ToNot(HaveKey("last"))
+
+		p.Data = make([]pet, 3)
+		Expect(p.Links(u)).ToNot(HaveKey("next"))
+	})
+
+	It("links cursor pages with signed tokens", func() {
+		p := &Paginated{Page: Page{Cursor: "5", Limit: 10}, NextCursor: "15"}
+		links := p.Links(u)
+		Expect(links).To(HaveKey("first"))
+		Expect(links).ToNot(HaveKey("last"))
+
+		next, _ := url.Parse(links["next"])
+		token := next.Query().Get("page[cursor]")
+		Expect(next.Query().Get("page[offset]")).To(BeEmpty())
+
+		cursor, err := DecodeCursor(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cursor).To(Equal("15"))
+
+		_, err = DecodeCursor(EncodeCursor("16")[:4] + token[4:])
+		Expect(err).To(HaveOccurred())
+		_, err = DecodeCursor("15")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("with AddResource", func() {
+		var api *API
+
+		BeforeEach(func() {
+			source := &pagedPetSource{crudPetSource{pets: map[string]*crudPet{}}}
+			for _, name := range []string{"simba", "nala", "kiara"} {
+				source.Create(WithModel(context.Background(), &crudPet{Name: name}))
+			}
+
+			api = New("/v1")
+			api.AddResource("pets", source)
+		})
+
+		It("sends headers", func() {
+			w := serve(api, "GET", "/v1/pets?page[limit]=2", "", "Accept", "application/json")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`[{"id":"1","name":"simba"},{"id":"2","name":"nala"}]`))
+			Expect(w.Header().Get("X-Total-Count")).To(Equal("3"))
+			Expect(w.Header().Get("Link")).To(Equal(`</v1/pets?page%5Blimit%5D=2&page%5Boffset%5D=0>; rel="first", </v1/pets?page%5Blimit%5D=2&page%5Boffset%5D=2>; rel="next", </v1/pets?page%5Blimit%5D=2&page%5Boffset%5D=2>; rel="last"`))
+		})
+
+		It("follows cursors", func() {
+			w := serve(api, "GET", "/v1/pets?page[limit]=1&page[cursor]="+EncodeCursor("1"), "", "Accept", "application/json")
+			Expect(w.Body.String()).To(MatchJSON(`[{"id":"2","name":"nala"}]`))
+			Expect(w.Header().Get("Link")).To(ContainSubstring(EncodeCursor("2")))
+
+			w = serve(api, "GET", "/v1/pets?page[cursor]=1", "", "Accept", "application/json")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("sends meta.total and links in JSON:API documents", func() {
+			w := serve(api, "GET", "/v1/pets?page[limit]=2&page[offset]=2", "", "Accept", JSONAPIMediaType)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{
+				"data":[{"type":"pets","id":"3","attributes":{"name":"kiara"}}],
+				"links":{
+					"first":"/v1/pets?page%5Blimit%5D=2&page%5Boffset%5D=0",
+					"prev":"/v1/pets?page%5Blimit%5D=2&page%5Boffset%5D=0",
+					"last":"/v1/pets?page%5Blimit%5D=2&page%5Boffset%5D=2"
+				},
+				"meta":{"total":3}
+			}`))
+		})
+	})
+})
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	MergePatchMediaType = "application/merge-patch+json" // RFC 7396
+	JSONPatchMediaType  = "application/json-patch+json"  // RFC 6902
+)
+
+// Patch is the body of a partial update, applied by Resource.Update
+// to the model found by the DataSource.
+type Patch struct {
+	MediaType string // MergePatchMediaType or JSONPatchMediaType
+	Body      []byte
+}
+
+// Apply applies a patch to a JSON document.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	switch p.MediaType {
+	case MergePatchMediaType:
+		return MergePatch(doc, p.Body)
+	case JSONPatchMediaType:
+		return JSONPatch(doc, p.Body)
+	}
+	return nil, unsupportedMediaType(p.MediaType, []string{MergePatchMediaType, JSONPatchMediaType})
+}
+
+// isPatchMediaType reports whether a request body is a Patch.
+func isPatchMediaType(mediaType string) bool {
+	return mediaType == MergePatchMediaType || mediaType == JSONPatchMediaType
+}
+
+type patchKey struct{}
+
+// WithPatch returns a context holding the Patch of a request.
+func WithPatch(ctx context.Context, p Patch) context.Context {
+	return context.WithValue(ctx, patchKey{}, p)
+}
+
+// PatchFrom returns the Patch held by a context, if any.
+func PatchFrom(ctx context.Context) (Patch, bool) {
+	p, ok := ctx.Value(patchKey{}).(Patch)
+	return p, ok
+}
+
+// MergePatch applies a JSON Merge Patch to a JSON document.
+// A malformed patch is a 400 Error.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, invalidPatch("The patch is not a JSON document")
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+// JSONPatch applies the operations of a JSON Patch to a JSON document.
+// A malformed patch is a 400 Error, and a failing operation
+// a 422 Error whose source points at the operation, i.e. /2.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, invalidPatch("The patch is not an array of operations")
+	}
+
+	for i, raw := range ops {
+		op, err := parsePatchOp(raw)
+		if err == nil {
+			target, err = op.apply(target)
+		}
+		if err != nil {
+			e := NewError(http.StatusUnprocessableEntity, "Invalid patch")
+			e.Code = "invalid_patch"
+			e.Detail = fmt.Sprintf("Operation %d %s", i, err)
+			if op.op != "" {
+				e.Detail = fmt.Sprintf("Operation %d (%s %s) %s", i, op.op, op.pointer, err)
+			}
+			e.Source = &ErrorSource{Pointer: "/" + strconv.Itoa(i)}
+			return nil, e
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+type patchOp struct {
+	op       string
+	pointer  string
+	path     []string
+	from     []string
+	value    interface{}
+	hasValue bool
+}
+
+func parsePatchOp(raw map[string]json.RawMessage) (patchOp, error) {
+	var op patchOp
+	if err := json.Unmarshal(raw["op"], &op.op); err != nil {
+		return op, fmt.Errorf("must have an op")
+	}
+
+	json.Unmarshal(raw["path"], &op.pointer)
+
+	var err error
+	if op.path, err = parsePointer(raw["path"]); err != nil {
+		return op, fmt.Errorf("must have a path: %v", err)
+	}
+
+	switch op.op {
+	case "add", "replace", "test":
+		value, ok := raw["value"]
+		if !ok {
+			return op, fmt.Errorf("must have a value")
+		}
+		op.value, _ = decodeJSON(value)
+		op.hasValue = true
+	case "move", "copy":
+		if op.from, err = parsePointer(raw["from"]); err != nil {
+			return op, fmt.Errorf("must have a from: %v", err)
+		}
+	case "remove":
+	default:
+		return op, fmt.Errorf("is not an operation")
+	}
+
+	return op, nil
+}
+
+// parsePointer parses a JSON pointer into its reference tokens.
+func parsePointer(raw json.RawMessage) ([]string, error) {
+	var pointer string
+	if err := json.Unmarshal(raw, &pointer); err != nil {
+		return nil, fmt.Errorf("not a string")
+	}
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q does not start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func (op patchOp) apply(doc interface{}) (interface{}, error) {
+	switch op.op {
+	case "add":
+		return addValue(doc, op.path, op.value)
+
+	case "remove":
+		doc, _, err := removeValue(doc, op.path)
+		return doc, err
+
+	case "replace":
+		if _, err := getValue(doc, op.path); err != nil {
+			return nil, err
+		}
+		if len(op.path) == 0 {
+			return op.value, nil
+		}
+		doc, _, err := removeValue(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, op.path, op.value)
+
+	case "move":
+		if isPrefix(op.from, op.path) && len(op.from) < len(op.path) {
+			return nil, fmt.Errorf("cannot move a value into itself")
+		}
+		doc, value, err := removeValue(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, op.path, value)
+
+	case "copy":
+		value, err := getValue(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(value)
+		value, _ = decodeJSON(b)
+		return addValue(doc, op.path, value)
+
+	case "test":
+		value, err := getValue(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(value, op.value) {
+			return nil, fmt.Errorf("failed")
+		}
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("is not an operation")
+}
+
+// getValue returns the value a pointer refers to.
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("refers to a missing member %q", token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("refers to a missing member %q", token)
+		}
+	}
+	return doc, nil
+}
+
+// addValue adds a value to the object or array a pointer refers to.
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return updateParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("refers to a missing member %q", token)
+	})
+}
+
+// removeValue removes the value a pointer refers to, and returns it.
+func removeValue(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	var removed interface{}
+	doc, err := updateParent(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("refers to a missing member %q", token)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("refers to a missing member %q", token)
+	})
+	return doc, removed, err
+}
+
+// updateParent replaces the parent of the value a pointer refers to
+// with the result of f.
+func updateParent(doc interface{}, path []string, f func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return f(doc, path[0])
+	}
+
+	child, err := getValue(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = updateParent(child, path[1:], f)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index up to max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("refers to an invalid array index %q", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("refers to an out of bounds array index %d", i)
+	}
+	return i, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual compares JSON values, with numbers compared by value.
+func jsonEqual(a, b interface{}) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			if other, ok := b[key]; !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// decodeJSON decodes a JSON document, keeping numbers as json.Number.
+func decodeJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func invalidPatch(detail string) *Error {
+	err := NewError(http.StatusBadRequest, "Invalid patch")
+	err.Code = "invalid_patch"
+	err.Detail = detail
+	return err
+}
+
+// applyPatch applies a Patch to the model found by the DataSource,
+// validates the patched model and puts it on the context, see WithModel.
+// Fields hidden from JSON are kept from the model found.
+func (r *Resource) applyPatch(ctx context.Context, patch Patch) (context.Context, error) {
+	c, err := r.Source.FindOne(ctx)
+	if err != nil {
+		return c, err
+	}
+
+	current := ResultFrom(c).Data
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return ctx, err
+	}
+
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return ctx, err
+	}
+
+	t := bodyType(current)
+	model := reflect.New(t)
+	if err := json.Unmarshal(patched, model.Interface()); err != nil {
+		e := NewError(http.StatusUnprocessableEntity, "Invalid patch")
+		e.Code = "invalid_patch"
+		e.Detail = "The patched model is invalid: " + err.Error()
+		if te, ok := err.(*json.UnmarshalTypeError); ok && te.Field != "" {
+			e.Source = &ErrorSource{Pointer: "/" + strings.Replace(te.Field, ".", "/", -1)}
+		}
+		return ctx, e
+	}
+
+	if t.Kind() == reflect.Struct {
+		src := indirect(reflect.ValueOf(current))
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" && f.Tag.Get("json") == "-" {
+				model.Elem().Field(i).Set(src.Field(i))
+			}
+		}
+	}
+
+	// Patches cannot move the models of nested resources to other parents.
+	if p, ok := ParentFrom(ctx); ok {
+		if err := setField(model.Interface(), p.Field, p.ID); err != nil {
+			return ctx, WrapErr(err, http.StatusInternalServerError)
+		}
+	}
+
+	if err := Validate(model.Interface()); err != nil {
+		return ctx, err
+	}
+
+	return WithModel(ctx, model.Interface()), nil
+}
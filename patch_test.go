@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Patch", func() {
+	Describe("MergePatch", func() {
+		It("merges objects and removes nulls", func() {
+			b, err := MergePatch(
+				[]byte(`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`),
+				[]byte(`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(MatchJSON(`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`))
+
+			b, _ = MergePatch([]byte(`{"a":"b"}`), []byte(`["c"]`))
+			Expect(b).To(MatchJSON(`["c"]`))
+		})
+
+		It("rejects malformed patches", func() {
+			_, err := MergePatch([]byte(`{}`), []byte(`{`))
+			Expect(err.(*Error).Status).To(Equal("400"))
+		})
+	})
+
+	Describe("JSONPatch", func() {
+		apply := func(doc, patch string) (string, error) {
+			b, err := JSONPatch([]byte(doc), []byte(patch))
+			return string(b), err
+		}
+
+		It("applies operations", func() {
+			for _, c := range []struct{ doc, patch, expected string }{
+				{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+				{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+				{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+				{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+				{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+				{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":null}]`, `{"baz":null,"foo":"bar"}`},
+				{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+				{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+				{`{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`},
+				{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+				{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+				{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":"qux"}}]`, `{"baz":"qux"}`},
+			} {
+				b, err := apply(c.doc, c.patch)
+				Expect(err).ToNot(HaveOccurred(), c.patch)
+				Expect(b).To(MatchJSON(c.expected), c.patch)
+			}
+		})
+
+		It("points at the failing operation", func() {
+			for _, c := range []struct{ patch, pointer, detail string }{
+				{`[{"op":"test","path":"/foo/0","value":"bar"},{"op":"test","path":"/foo/0","value":"baz"}]`, "/1", "Operation 1 (test /foo/0) failed"},
+				{`[{"op":"add","path":"/baz/bat","value":"qux"}]`, "/0", `Operation 0 (add /baz/bat) refers to a missing member "baz"`},
+				{`[{"op":"replace","path":"/foo/9","value":1}]`, "/0", "Operation 0 (replace /foo/9) refers to an out of bounds array index 9"},
+				{`[{"op":"remove","path":"/foo/01"}]`, "/0", `Operation 0 (remove /foo/01) refers to an invalid array index "01"`},
+				{`[{"op":"move","from":"/foo","path":"/foo/0"}]`, "/0", "Operation 0 (move /foo/0) cannot move a value into itself"},
+				{`[{"op":"add","path":"/a"}]`, "/0", "Operation 0 (add /a) must have a value"},
+				{`[{"op":"merge","path":"/a"}]`, "/0", "Operation 0 (merge /a) is not an operation"},
+				{`[{"path":"/a"}]`, "/0", "Operation 0 must have an op"},
+			} {
+				_, err := apply(`{"foo":["bar"]}`, c.patch)
+				Expect(err).To(HaveOccurred(), c.patch)
+
+				e := err.(*Error)
+				Expect(e.Status).To(Equal("422"))
+				Expect(e.Source.Pointer).To(Equal(c.pointer))
+				Expect(e.Detail).To(Equal(c.detail))
+			}
+
+			_, err := apply(`{}`, `{"op":"add"}`)
+			Expect(err.(*Error).Status).To(Equal("400"))
+		})
+	})
+
+	Describe("with AddResource", func() {
+		var (
+			api    *API
+			source *MemorySource
+		)
+
+		patch := func(contentType, body string) *httptest.ResponseRecorder {
+			r, _ := http.NewRequest("PATCH", "/pets/1", strings.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, r)
+			return w
+		}
+
+		BeforeEach(func() {
+			source = NewMemorySource(memoryPet{})
+			owner := "mufasa"
+			source.Create(WithModel(context.Background(), &memoryPet{Name: "simba", Age: 3, Owner: &owner}))
+
+			api = New("")
+			api.AddResource("pets", source, ResourceModel(memoryPet{}))
+		})
+
+		It("applies merge patches to the current model", func() {
+			w := patch(MergePatchMediaType, `{"age":0,"owner":null}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"simba","age":0,"born":"0001-01-01T00:00:00Z","owner":null,"version":2}`))
+		})
+
+		It("applies JSON patches to the current model", func() {
+			w := patch(JSONPatchMediaType, `[{"op":"test","path":"/name","value":"simba"},{"op":"replace","path":"/age","value":4}]`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"age":4,"born":"0001-01-01T00:00:00Z","owner":"mufasa"`))
+
+			w = patch(JSONPatchMediaType, `[{"op":"test","path":"/name","value":"nala"}]`)
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring(`"pointer":"/0"`))
+		})
+
+		It("validates the patched model", func() {
+			w := patch(MergePatchMediaType, `{"age":"old"}`)
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring(`"pointer":"/age"`))
+
+			api = New("")
+			api.AddResource("pets", NewMemorySource(crudPet{}), ResourceModel(crudPet{}))
+			api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/pets", strings.NewReader(`{"name":"simba"}`)))
+
+			w = patch(JSONPatchMediaType, `[{"op":"remove","path":"/name"}]`)
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring(`"pointer":"/name"`))
+		})
+
+		It("keeps fields hidden from JSON", func() {
+			ctx, _ := source.FindOne(WithID(context.Background(), "1"))
+			seen := ResultFrom(ctx).Data.(*memoryPet)
+			now := seen.Born
+			seen.Seen = &now
+			source.Update(WithModel(WithID(context.Background(), "1"), seen))
+
+			Expect(patch(MergePatchMediaType, `{"name":"nala"}`).Code).To(Equal(http.StatusOK))
+
+			ctx, _ = source.FindOne(WithID(context.Background(), "1"))
+			Expect(ResultFrom(ctx).Data.(*memoryPet).Seen).ToNot(BeNil())
+		})
+
+		It("returns 404 for missing models", func() {
+			r, _ := http.NewRequest("PATCH", "/pets/9", strings.NewReader(`{}`))
+			r.Header.Set("Content-Type", MergePatchMediaType)
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Query describes what a DataSource should find, update or delete.
+// It is parsed from a request by ParseQuery,
+// and passed to DataSources with WithQuery.
+type Query struct {
+	ID      string              // Id of the model, from the :id path parameter
+	IDs     []string            // Ids of the models of bulk requests, from id=a,b,c
+	Filters []Filter            // From filter[field]=value and filter[field][op]=value, all of which must match
+	Sort    []SortField         // From sort=field,-field
+	Page    Page                // From page[offset], page[limit] or page[size], and page[cursor]
+	Fields  map[string][]string // Sparse fieldsets, from fields[type]=field,field
+	Include []string            // Related models to include, from include=relation,relation
+
+	// IncludeDeleted makes DataSources find soft deleted models too,
+	// from include_deleted=true, see SoftDeleter.
+	IncludeDeleted bool
+}
+
+// Operator compares a field to the values of a Filter.
+type Operator string
+
+const (
+	OpEq   Operator = "eq"   // Equal to the value
+	OpNe   Operator = "ne"   // Not equal to the value
+	OpLt   Operator = "lt"   // Lower than the value
+	OpGt   Operator = "gt"   // Greater than the value
+	OpIn   Operator = "in"   // Equal to one of the comma-separated values
+	OpLike Operator = "like" // Matches the value, where % matches any string
+	OpNull Operator = "null" // Null when the value is true, not null when false
+)
+
+// Operators lists every Operator.
+var Operators = []Operator{OpEq, OpNe, OpLt, OpGt, OpIn, OpLike, OpNull}
+
+// Filter restricts the models found to the ones whose Field
+// compares to Values with Op.
+type Filter struct {
+	Field  string
+	Op     Operator
+	Values []string
+}
+
+// SortField orders models by a field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Page selects a window of the models found,
+// either from an Offset or after a Cursor.
+// Cursors are sent to clients as opaque tokens, see EncodeCursor.
+// A zero Limit means no limit.
+type Page struct {
+	Offset int
+	Limit  int
+	Cursor string
+}
+
+// Result carries what a DataSource found.
+type Result struct {
+	Data interface{} // A model, or a slice of models
+
+	// Total is the number of models matching the filters of the query,
+	// regardless of its page, or nil when unknown.
+	Total *int
+
+	// Cursors of the next and previous pages, for cursor pagination.
+	// Empty when there is no such page.
+	NextCursor string
+	PrevCursor string
+}
+
+// QueryRules is the allow-list of the query parameters of an endpoint.
+// Fields which are not listed are reported with a 400 Errors.
+type QueryRules struct {
+	Filters  map[string][]Operator // Filterable fields, with their operators or nil for all operators
+	Sort     []string              // Sortable fields
+	MaxLimit int                   // Maximum page limit, 0 means no maximum
+
+	// DefaultLimit is the page limit of queries without one.
+	// It is capped at MaxLimit, and defaults to MaxLimit.
+	DefaultLimit int
+}
+
+// QueryParser is a RequestParser putting the Query
+// of a request on the context, see WithQuery.
+type QueryParser struct {
+	Rules *QueryRules // Optional
+}
+
+func (p QueryParser) ParseRequest(ctx context.Context, r *Req) (context.Context, error) {
+	q, err := p.Rules.Parse(r)
+	if err != nil {
+		return ctx, err
+	}
+	return WithQuery(ctx, q), nil
+}
+
+// ParseQuery parses the Query of a request, allowing any field.
+// Malformed parameters are reported with a 400 Errors.
+func ParseQuery(r *Req) (Query, error) {
+	return (*QueryRules)(nil).Parse(r)
+}
+
+// Parse parses the Query of a request.
+// Malformed parameters and fields which are not allowed
+// are reported with a 400 Errors.
+// A nil QueryRules allows any field.
+func (rules *QueryRules) Parse(r *Req) (Query, error) {
+	return rules.parse(r, true)
+}
+
+// parse parses the Query of a request, applying the DefaultLimit
+// and MaxLimit of the rules only to paginated requests.
+func (rules *QueryRules) parse(r *Req, paginated bool) (Query, error) {
+	if rules != nil && !paginated {
+		unlimited := *rules
+		unlimited.MaxLimit, unlimited.DefaultLimit = 0, 0
+		rules = &unlimited
+	}
+
+	if r.Params == nil {
+		r.Params = new(Params)
+	}
+	if r.Params.Values == nil {
+		if err := r.ParseParams(); err != nil {
+			return Query{}, WrapErr(err, http.StatusBadRequest)
+		}
+	}
+
+	values := r.Params.Query
+	if values == nil {
+		values = Values{}
+	}
+
+	opts := ParseDocumentOptions(values)
+	q := Query{
+		ID:      r.Params.Get(":id"),
+		IDs:     r.Params.Values["id"],
+		Fields:  opts.Fields,
+		Include: opts.Include,
+	}
+
+	// Map iteration is random: sort keys for a deterministic Query.
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs Errors
+	for _, key := range keys {
+		switch {
+		case strings.HasPrefix(key, "filter["):
+			f, err := rules.filter(key, values[key])
+			if err != nil {
+				errs.Add(err)
+				continue
+			}
+			q.Filters = append(q.Filters, f)
+
+		case strings.HasPrefix(key, "page["):
+			if err := rules.page(&q.Page, key, values.Get(key)); err != nil {
+				errs.Add(err)
+			}
+		}
+	}
+
+	if v := values.Get("include_deleted"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs.Add(queryError("include_deleted", "must be true or false"))
+		}
+		q.IncludeDeleted = b
+	}
+
+	if q.Page.Limit == 0 {
+		q.Page.Limit = rules.defaultLimit()
+	}
+
+	if q.Page.Cursor != "" && q.Page.Offset != 0 {
+		errs.Add(queryError("page[cursor]", "cannot be used with page[offset]"))
+	}
+
+	for _, field := range splitValues(values["sort"], ",") {
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+
+		if field == "" {
+			errs.Add(queryError("sort", "must list fields"))
+			break
+		}
+		if rules != nil && !contains(rules.Sort, field) {
+			errs.Add(queryError("sort", "cannot sort by "+field))
+			continue
+		}
+		q.Sort = append(q.Sort, SortField{Field: field, Desc: desc})
+	}
+
+	if len(errs.Err) > 0 {
+		return q, errs
+	}
+	return q, nil
+}
+
+// filter parses filter[field]=value and filter[field][op]=value.
+func (rules *QueryRules) filter(key string, values []string) (Filter, *Error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]"), "][")
+	if !strings.HasSuffix(key, "]") || len(parts) > 2 || parts[0] == "" {
+		return Filter{}, queryError(key, "must be filter[field] or filter[field][operator]")
+	}
+
+	f := Filter{Field: parts[0], Op: OpEq}
+	if len(parts) == 2 {
+		f.Op = Operator(parts[1])
+		if !containsOperator(Operators, f.Op) {
+			return f, queryError(key, "has an unknown operator "+parts[1])
+		}
+	}
+
+	if rules != nil {
+		ops, ok := rules.Filters[f.Field]
+		if !ok {
+			return f, queryError(key, "cannot filter by "+f.Field)
+		}
+		if ops != nil && !containsOperator(ops, f.Op) {
+			return f, queryError(key, "cannot filter "+f.Field+" with "+string(f.Op))
+		}
+	}
+
+	switch f.Op {
+	case OpIn:
+		f.Values = splitValues(values, ",")
+	case OpNull:
+		if len(values) != 1 || (values[0] != "true" && values[0] != "false") {
+			return f, queryError(key, "must be true or false")
+		}
+		f.Values = values
+	default:
+		if len(values) != 1 {
+			return f, queryError(key, "must have a single value")
+		}
+		f.Values = values
+	}
+
+	return f, nil
+}
+
+// page parses page[offset], page[limit], page[size] and page[cursor].
+func (rules *QueryRules) page(p *Page, key, value string) *Error {
+	if key == "page[cursor]" {
+		cursor, err := DecodeCursor(value)
+		if err != nil {
+			return queryError(key, "is invalid")
+		}
+		p.Cursor = cursor
+		return nil
+	}
+
+	if key != "page[offset]" && key != "page[limit]" && key != "page[size]" {
+		return queryError(key, "is not a page parameter")
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return queryError(key, "must be a positive integer")
+	}
+
+	if key == "page[offset]" {
+		p.Offset = n
+		return nil
+	}
+
+	if rules != nil && rules.MaxLimit > 0 {
+		// A zero limit means no limit.
+		if n == 0 || n > rules.MaxLimit {
+			return queryError(key, "must be between 1 and "+strconv.Itoa(rules.MaxLimit))
+		}
+	}
+	p.Limit = n
+	return nil
+}
+
+// defaultLimit returns the page limit of queries without one.
+func (rules *QueryRules) defaultLimit() int {
+	if rules == nil {
+		return 0
+	}
+	if rules.DefaultLimit > 0 && (rules.MaxLimit == 0 || rules.DefaultLimit < rules.MaxLimit) {
+		return rules.DefaultLimit
+	}
+	return rules.MaxLimit
+}
+
+func containsOperator(ops []Operator, op Operator) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func queryError(param, detail string) *Error {
+	err := NewError(http.StatusBadRequest, "Invalid query")
+	err.Code = "invalid_query"
+	err.Detail = param + " " + detail
+	err.Source = &ErrorSource{Parameter: param}
+	return err
+}
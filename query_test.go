@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Query", func() {
+	parse := func(url string) (Query, error) {
+		r, _ := http.NewRequest("GET", url, nil)
+		return ParseQuery(NewReq(nil, r, &Params{Path: Values{":id": {"7"}}}))
+	}
+
+	It("parses filters, sort, pagination, fields and includes", func() {
+		q, err := parse("/pets?filter[name]=simba&filter[age]=3&sort=-age,name&page[offset]=10&page[limit]=5&fields[pets]=name,age&include=owner")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(q).To(Equal(Query{
+			ID: "7",
+			Filters: []Filter{
+				{Field: "age", Op: "eq", Values: []string{"3"}},
+				{Field: "name", Op: "eq", Values: []string{"simba"}},
+			},
+			Sort:    []SortField{{Field: "age", Desc: true}, {Field: "name"}},
+			Page:    Page{Offset: 10, Limit: 5},
+			Fields:  map[string][]string{"pets": {"name", "age"}},
+			Include: []string{"owner"},
+		}))
+	})
+
+	It("reports malformed parameters", func() {
+		_, err := parse("/pets?page[limit]=-1&sort=,")
+		Expect(err).To(HaveOccurred())
+
+		errs := err.(Errors)
+		Expect(errs.HTTPStatus()).To(Equal(http.StatusBadRequest))
+		Expect(errs.Err).To(HaveLen(2))
+		Expect(errs.Err[0].Source.Parameter).To(Equal("page[limit]"))
+	})
+
+	It("parses operators", func() {
+		q, err := parse("/pets?filter[age][gt]=3&filter[name][in]=simba,nala&filter[owner][null]=false&filter[tag][like]=%25cat%25&page[size]=20&page[cursor]=" + EncodeCursor("abc"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(q.Filters).To(Equal([]Filter{
+			{Field: "age", Op: OpGt, Values: []string{"3"}},
+			{Field: "name", Op: OpIn, Values: []string{"simba", "nala"}},
+			{Field: "owner", Op: OpNull, Values: []string{"false"}},
+			{Field: "tag", Op: OpLike, Values: []string{"%cat%"}},
+		}))
+		Expect(q.Page).To(Equal(Page{Limit: 20, Cursor: "abc"}))
+	})
+
+	It("reports malformed filters", func() {
+		_, err := parse("/pets?filter[age][gte]=3&filter[owner][null]=maybe&filter[a][b][c]=1&page[offset]=1&page[cursor]=" + EncodeCursor("abc"))
+		Expect(err).To(HaveOccurred())
+
+		var details []string
+		for _, e := range err.(Errors).Err {
+			details = append(details, e.Detail)
+		}
+		Expect(details).To(Equal([]string{
+			"filter[a][b][c] must be filter[field] or filter[field][operator]",
+			"filter[age][gte] has an unknown operator gte",
+			"filter[owner][null] must be true or false",
+			"page[cursor] cannot be used with page[offset]",
+		}))
+	})
+
+	It("only allows the fields of its rules", func() {
+		rules := &QueryRules{
+			Filters:  map[string][]Operator{"name": nil, "age": {OpLt, OpGt}},
+			Sort:     []string{"name"},
+			MaxLimit: 50,
+		}
+
+		parseWith := func(url string) (Query, error) {
+			r, _ := http.NewRequest("GET", url, nil)
+			return rules.Parse(WrapReq(nil, r))
+		}
+
+		_, err := parseWith("/pets?filter[name][like]=s%25&filter[age][lt]=3&sort=-name&page[limit]=50")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = parseWith("/pets?filter[secret]=1&filter[age]=3&sort=age&page[limit]=51")
+		Expect(err).To(HaveOccurred())
+
+		var params []string
+		for _, e := range err.(Errors).Err {
+			Expect(e.Status).To(Equal("400"))
+			params = append(params, e.Source.Parameter)
+		}
+		Expect(params).To(Equal([]string{"filter[age]", "filter[secret]", "page[limit]", "sort"}))
+	})
+
+	It("enforces the maximum page limit", func() {
+		parseWith := func(rules *QueryRules, url string) (Query, error) {
+			r, _ := http.NewRequest("GET", url, nil)
+			return rules.Parse(WrapReq(nil, r))
+		}
+
+		rules := &QueryRules{MaxLimit: 50}
+		_, err := parseWith(rules, "/pets?page[limit]=0")
+		Expect(err).To(HaveOccurred())
+
+		q, err := parseWith(rules, "/pets")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(q.Page.Limit).To(Equal(50))
+
+		rules.DefaultLimit = 10
+		q, _ = parseWith(rules, "/pets?page[offset]=20")
+		Expect(q.Page).To(Equal(Page{Offset: 20, Limit: 10}))
+
+		rules.DefaultLimit = 100
+		q, _ = parseWith(rules, "/pets")
+		Expect(q.Page.Limit).To(Equal(50))
+
+		q, _ = parseWith(nil, "/pets?page[limit]=0")
+		Expect(q.Page.Limit).To(BeZero())
+	})
+
+	It("is passed to the DataSource by HandleIndex", func() {
+		var found Query
+		source := &crudPetSource{pets: map[string]*crudPet{}}
+		index := func(ctx context.Context, r *Req) {
+			res := NewResource(r, source)
+			ctx, err := res.HandleIndex(ctx, noopParser{})
+			if err != nil {
+				res.HandleError(err)
+				return
+			}
+			found = QueryFrom(ctx)
+		}
+
+		r, _ := http.NewRequest("GET", "/pets?sort=name&filter[name]=simba", nil)
+		index(context.Background(), WrapReq(httptest.NewRecorder(), r))
+		Expect(found.Sort).To(Equal([]SortField{{Field: "name"}}))
+		Expect(found.Filters).To(HaveLen(1))
+	})
+
+	It("is carried by the context", func() {
+		ctx := WithQuery(context.Background(), Query{Include: []string{"owner"}})
+		ctx = WithID(ctx, "1")
+
+		Expect(IDFrom(ctx)).To(Equal("1"))
+		Expect(QueryFrom(ctx).Include).To(Equal([]string{"owner"}))
+		Expect(ResultFrom(ctx).Data).To(BeNil())
+	})
+})
+
+type noopParser struct{}
+
+func (noopParser) ParseRequest(ctx context.Context, r *Req) (context.Context, error) {
+	return ctx, nil
+}
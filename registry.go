@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// RouterAdapter wraps a router value into a Router.
+// It returns false when it does not support the type of the value.
+type RouterAdapter func(v interface{}) (Router, bool)
+
+type namedAdapter struct {
+	name  string
+	adapt RouterAdapter
+}
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   []namedAdapter
+)
+
+// RegisterRouter teaches WrapRouter, and therefore API.Activate,
+// how to wrap a new type of router.
+// Adapters are tried from the most recently registered to the first one,
+// so a package can take over a router type supported by a previous adapter.
+func RegisterRouter(name string, adapter RouterAdapter) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+
+	adapters = append(adapters, namedAdapter{name: name, adapt: adapter})
+}
+
+// RegisteredRouters returns the names of the registered router adapters.
+func RegisteredRouters() []string {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+
+	names := make([]string, len(adapters))
+	for i, a := range adapters {
+		names[i] = a.name
+	}
+	return names
+}
+
+// Wrap a router to be used with Activate
+// i.e. api.Activate(WrapRouter(router))
+func WrapRouter(v interface{}) (Router, error) {
+	if r, ok := v.(Router); ok {
+		return r, nil
+	}
+
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+
+	for i := len(adapters) - 1; i >= 0; i-- {
+		if r, ok := adapters[i].adapt(v); ok {
+			return r, nil
+		}
+	}
+
+	names := make([]string, len(adapters))
+	for i, a := range adapters {
+		names[i] = a.name
+	}
+
+	return nil, fmt.Errorf("Cannot wrap unsupported router type %T (supported: %s)", v, strings.Join(names, ", "))
+}
+
+func init() {
+	// Interfaces first, so adapters for concrete types are tried before them.
+	RegisterRouter("pat", func(v interface{}) (Router, bool) {
+		r, ok := v.(patRouter)
+		return &patAdapter{r: r}, ok
+	})
+
+	RegisterRouter("RegisterFunc", func(v interface{}) (Router, bool) {
+		switch r := v.(type) {
+		case RegisterFunc:
+			return registerFuncAdapter{r}, true
+		case func(method, path string, h http.Handler):
+			return registerFuncAdapter{r}, true
+		}
+		return nil, false
+	})
+
+	RegisterRouter("httprouter", func(v interface{}) (Router, bool) {
+		r, ok := v.(*httprouter.Router)
+		return &httprouterAdapter{r}, ok
+	})
+
+	RegisterRouter("http.ServeMux", func(v interface{}) (Router, bool) {
+		r, ok := v.(*http.ServeMux)
+		return &serveMuxAdapter{r}, ok
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type customRouter struct {
+	routes map[string]Handler
+}
+
+func (r *customRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h, ok := r.routes[req.Method+" "+req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	h.Serve(context.Background(), WrapReq(w, req))
+}
+
+type customAdapter struct {
+	r *customRouter
+}
+
+func (a customAdapter) Add(method, path string, h Handler) {
+	a.r.routes[method+" "+path] = h
+}
+
+var _ = Describe("Router registry", func() {
+	It("lists the built-in adapters", func() {
+		Expect(RegisteredRouters()).To(ContainElement("httprouter"))
+		Expect(RegisteredRouters()).To(ContainElement("pat"))
+		Expect(RegisteredRouters()).To(ContainElement("http.ServeMux"))
+	})
+
+	It("reports the supported types of routers", func() {
+		_, err := WrapRouter("not a router")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("string"))
+		Expect(err.Error()).To(ContainSubstring("httprouter"))
+	})
+
+	It("accepts a Router as is", func() {
+		r := customAdapter{&customRouter{routes: map[string]Handler{}}}
+		wrapped, err := WrapRouter(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(wrapped).To(Equal(r))
+	})
+
+	It("activates an API on a registered router type", func() {
+		adaptersMu.RLock()
+		saved := adapters
+		adaptersMu.RUnlock()
+		defer func() {
+			adaptersMu.Lock()
+			adapters = saved
+			adaptersMu.Unlock()
+		}()
+
+		RegisterRouter("custom", func(v interface{}) (Router, bool) {
+			r, ok := v.(*customRouter)
+			return customAdapter{r}, ok
+		})
+		Expect(RegisteredRouters()).To(ContainElement("custom"))
+
+		router := &customRouter{routes: map[string]Handler{}}
+		err := makeAPI().Activate(router)
+		Expect(err).ToNot(HaveOccurred())
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/pets", nil)
+		router.ServeHTTP(res, req)
+
+		Expect(res.Header().Get("X-Api-Version")).To(Equal("0.0.1"))
+		Expect(res.Code).To(Equal(401))
+	})
+})
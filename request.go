@@ -17,12 +17,14 @@ import (
 // Req is a HTTP request wrapper giving you easy access to the params
 // content type, and much more.
 type Req struct {
-	ID          string // The request id
-	Response    http.ResponseWriter
-	Request     *http.Request
-	Params      *Params // Parameters from URL and form (including multipart). Keep in ctx instead?
-	ContentType string  // Content-Type of the request
-	body        []byte
+	ID           string // The request id
+	Response     http.ResponseWriter
+	Request      *http.Request
+	Params       *Params // Parameters from URL and form (including multipart). Keep in ctx instead?
+	ContentType  string  // Content-Type of the request
+	body         []byte
+	consumes     []string // Media types accepted by the endpoint
+	cacheControl string   // Cache-Control directives of the endpoint
 }
 
 func NewReq(w http.ResponseWriter, r *http.Request, p *Params) *Req {
@@ -91,17 +93,54 @@ func (r *Req) JsonForm() ([]byte, error) {
 	return json.Marshal(r.Params.Form)
 }
 
-// Decode decodes a request body into the value pointed to by v.
+// Decode decodes a request body into the value pointed to by v,
+// with the Decoder registered for the content type of the request.
+// Requests without a Content-Type are decoded as JSON.
+// It returns a 415 Error when the content type is not supported,
+// by the registered decoders or by the endpoint.
 func (r *Req) Decode(v interface{}) error {
+	mediaType := "application/json"
+	if r.Request.Header.Get("Content-Type") != "" {
+		mediaType = r.ResolveContentType()
+	}
+
+	if len(r.consumes) > 0 && !acceptsMediaType(r.consumes, mediaType) {
+		return unsupportedMediaType(mediaType, r.consumes)
+	}
+
+	d, ok := DecoderFor(mediaType)
+	if !ok {
+		return unsupportedMediaType(mediaType, nil)
+	}
+
+	body, err := r.readBody()
+	if err != nil {
+		return err
+	}
+
+	// The body of forms has already been read by ParseParams.
+	if mediaType == "application/x-www-form-urlencoded" && r.Request.PostForm != nil {
+		body = []byte(r.Request.PostForm.Encode())
+	}
+
+	return d.Decode(body, v)
+}
+
+// readBody reads the request body once, so it can be decoded several times.
+func (r *Req) readBody() ([]byte, error) {
 	if r.body == nil {
+		if r.Request.Body == nil {
+			return []byte{}, nil
+		}
+
 		b, err := ioutil.ReadAll(r.Request.Body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		r.body = b
 	}
 
-	return json.Unmarshal(r.body, v)
+	return r.body, nil
 }
 
 // handlePanic is a function usually used in defer
@@ -71,7 +71,6 @@ var _ = Describe("*Req", func() {
 			req := WrapReq(rec, r)
 			defer req.handlePanic()
 			panic("test")
-			return req
 		}
 
 		recorder := httptest.NewRecorder()
@@ -4,6 +4,7 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 
 	"golang.org/x/net/context"
 )
@@ -21,9 +22,32 @@ type ResponseMarshaller interface {
 type Resource struct {
 	Req    *Req
 	Source DataSource
+
+	// RequireIfMatch makes updates and deletes without an If-Match header
+	// fail with 428 Precondition Required.
+	RequireIfMatch bool
+
+	// SoftDelete makes Delete mark models as deleted
+	// when the Source is a SoftDeleter.
+	SoftDelete bool
+
+	// AuthorizeDeleted authorizes queries including soft deleted models,
+	// with include_deleted=true, when SoftDelete is set.
+	// Such queries are a 403 Forbidden when nil.
+	AuthorizeDeleted func(context.Context, *Req) error
+
+	// Hooks called around the actions of the resource.
+	Hooks Hooks
+
+	// OnHookError is called with the errors of AfterCreate, AfterUpdate
+	// and AfterDelete Hooks, which cannot veto writes which have happened.
+	// The errors are logged when nil.
+	OnHookError func(context.Context, HookPoint, *Error)
 }
 
 // DataSource provides methods needed for CRUD.
+// Implementations read the Query with QueryFrom and the model to write
+// with ModelFrom, and return what they found with WithResult.
 type DataSource interface {
 	// FindOne returns a model from a parsed query
 	FindOne(context.Context) (context.Context, error)
@@ -49,20 +73,49 @@ func NewResource(req *Req, source DataSource) *Resource {
 }
 
 func (r *Resource) Index(ctx context.Context) (context.Context, error) {
-	return r.Source.FindAll(ctx)
+	if err := r.authorizeDeleted(ctx); err != nil {
+		return ctx, err
+	}
+
+	c, err := r.Source.FindAll(ctx)
+	if err != nil {
+		return c, err
+	}
+
+	return c, r.hookEach(c, AfterIndex, ResultFrom(c).Data)
 }
 
+// HandleIndex parses the request and finds all models of the Query.
+// When the RequestParser does not put a Query on the context,
+// the Query of the request is parsed with ParseQuery.
 func (r *Resource) HandleIndex(ctx context.Context, rp RequestParser) (context.Context, error) {
 	c, err := rp.ParseRequest(ctx, r.Req)
 	if err != nil {
 		return c, err
 	}
 
+	if _, ok := c.Value(queryKey).(Query); !ok {
+		q, err := ParseQuery(r.Req)
+		if err != nil {
+			return c, err
+		}
+		c = WithQuery(c, q)
+	}
+
 	return r.Index(c)
 }
 
 func (r *Resource) Read(ctx context.Context) (context.Context, error) {
-	return r.Source.FindOne(ctx)
+	if err := r.authorizeDeleted(ctx); err != nil {
+		return ctx, err
+	}
+
+	c, err := r.Source.FindOne(ctx)
+	if err != nil {
+		return c, err
+	}
+
+	return c, r.hook(c, AfterRead, ResultFrom(c).Data)
 }
 
 func (r *Resource) HandleRead(ctx context.Context, rp RequestParser) (context.Context, error) {
@@ -75,12 +128,20 @@ func (r *Resource) HandleRead(ctx context.Context, rp RequestParser) (context.Co
 }
 
 func (r *Resource) Create(ctx context.Context) (context.Context, error) {
+	if err := r.hook(ctx, BeforeCreate, ModelFrom(ctx)); err != nil {
+		return ctx, err
+	}
+
 	c, err := r.Source.Create(ctx)
+	if err == nil {
+		c, err = r.Source.FindOne(c)
+	}
 	if err != nil {
 		return c, err
 	}
 
-	return r.Source.FindOne(c)
+	r.after(c, AfterCreate, ResultFrom(c).Data)
+	return c, nil
 }
 
 func (r *Resource) HandleCreate(ctx context.Context, rp RequestParser) (context.Context, error) {
@@ -93,13 +154,47 @@ func (r *Resource) HandleCreate(ctx context.Context, rp RequestParser) (context.
 	return r.Create(c)
 }
 
+// Update updates a model, if it matches the If-Match header of the request.
+// A Patch held by the context is applied to the model found by the DataSource.
 func (r *Resource) Update(ctx context.Context) (context.Context, error) {
-	c, err := r.Source.Update(ctx)
+	ifMatch, err := r.ifMatch()
+	if err != nil {
+		return ctx, err
+	}
+
+	if patch, ok := PatchFrom(ctx); ok {
+		if ctx, err = r.applyPatch(ctx, patch); err != nil {
+			return ctx, err
+		}
+	}
+
+	if err := r.hook(ctx, BeforeUpdate, ModelFrom(ctx)); err != nil {
+		return ctx, err
+	}
+
+	c, err := r.update(ctx, ifMatch)
+	if err == nil {
+		c, err = r.Source.FindOne(c)
+	}
 	if err != nil {
 		return c, err
 	}
 
-	return r.Source.FindOne(c)
+	r.after(c, AfterUpdate, ResultFrom(c).Data)
+	return c, nil
+}
+
+// update updates the model of the context, if it matches ifMatch.
+func (r *Resource) update(ctx context.Context, ifMatch string) (context.Context, error) {
+	if cas, ok := r.Source.(CompareAndSwapper); ok && ifMatch != "" {
+		return cas.UpdateIf(ctx, ifMatch)
+	}
+	if ifMatch != "" {
+		if c, err := r.checkIfMatch(ctx, ifMatch); err != nil {
+			return c, err
+		}
+	}
+	return r.Source.Update(ctx)
 }
 
 func (r *Resource) HandleUpdate(ctx context.Context, rp RequestParser) (context.Context, error) {
@@ -111,13 +206,51 @@ func (r *Resource) HandleUpdate(ctx context.Context, rp RequestParser) (context.
 	return r.Update(c)
 }
 
+// Delete deletes a model, if it matches the If-Match header of the request.
+// In SoftDelete mode, the model is marked as deleted instead.
 func (r *Resource) Delete(ctx context.Context) (context.Context, error) {
-	c, err := r.Source.FindOne(ctx)
+	ifMatch, err := r.ifMatch()
+	if err != nil {
+		return ctx, err
+	}
+
+	return r.delete(ctx, ifMatch)
+}
+
+// delete deletes the model of the id of the context, if it matches ifMatch.
+func (r *Resource) delete(ctx context.Context, ifMatch string) (context.Context, error) {
+	cas, atomic := r.Source.(CompareAndSwapper)
+	atomic = atomic && ifMatch != "" && !r.SoftDelete
+
+	var (
+		c   context.Context
+		err error
+	)
+	if ifMatch != "" && !atomic {
+		c, err = r.checkIfMatch(ctx, ifMatch)
+	} else {
+		c, err = r.Source.FindOne(ctx)
+	}
+	if err != nil {
+		return c, err
+	}
+
+	model := ResultFrom(c).Data
+	if err := r.hook(c, BeforeDelete, model); err != nil {
+		return c, err
+	}
+
+	if atomic {
+		c, err = cas.DeleteIf(c, ifMatch)
+	} else {
+		c, err = r.remove(c)
+	}
 	if err != nil {
 		return c, err
 	}
 
-	return r.Source.Delete(c)
+	r.after(c, AfterDelete, model)
+	return c, nil
 }
 
 func (r *Resource) HandleDelete(ctx context.Context, rp RequestParser) (context.Context, error) {
@@ -138,11 +271,14 @@ func HandleError(req *Req, err error) {
 }
 
 func handleError(req *Req, err error) {
-	apiErr, ok := err.(*Error)
+	apiErr, ok := err.(HTTPError)
 	if !ok {
 		apiErr = WrapErr(err, 500)
 	}
 
+	if req.Response.Header().Get("Content-Type") == "" {
+		req.Response.Header().Set("Content-Type", "application/json")
+	}
 	req.Response.WriteHeader(apiErr.HTTPStatus())
 	fmt.Fprintln(req.Response, apiErr.HTTPBody())
 }
@@ -155,19 +291,69 @@ func Send(ctx context.Context, req *Req, rm ResponseMarshaller) error {
 	return send(ctx, req, rm)
 }
 
+// send encodes the body of the response with the Encoder negotiated
+// from the Accept header of the request.
+// It returns a 406 Error, without writing the response,
+// when none of the registered media types is acceptable,
+// or none of the acceptable ModelEncoders can encode the body.
+// A nil body is sent as an empty body without a Content-Type,
+// rather than encoded as a JSON null.
+// Paginated bodies get their Link and X-Total-Count headers,
+// and only their Data is encoded, unless the Encoder is a RequestEncoder.
+// Successful responses get an ETag, Last-Modified and Cache-Control headers,
+// and conditional GET requests of unmodified bodies get a 304 Not Modified.
 func send(ctx context.Context, req *Req, rm ResponseMarshaller) error {
-	encoder := JsonEncoder{}
-	data, err := encoder.Encode(rm.Body(ctx))
-	if err != nil {
-		return err
+	body := rm.Body(ctx)
+	status := rm.Status(ctx)
+
+	var data []byte
+	if body != nil {
+		accept := req.Request.Header.Get("Accept")
+
+		mediaType, encoder, ok := negotiate(accept, body)
+		if !ok {
+			return notAcceptable(accept)
+		}
+
+		var err error
+		if re, ok := encoder.(RequestEncoder); ok {
+			data, err = re.EncodeRequest(req, body)
+		} else if p, ok := body.(*Paginated); ok {
+			data, err = encoder.Encode(p.Data)
+		} else {
+			data, err = encoder.Encode(body)
+		}
+		if err != nil {
+			return err
+		}
+
+		req.Response.Header().Set("Content-Type", mediaType)
+
+		if p, ok := body.(*Paginated); ok {
+			for key, val := range p.headers(req.Request.URL) {
+				req.Response.Header().Set(key, val)
+			}
+		}
+	}
+
+	if status >= 200 && status < 300 {
+		setValidators(ctx, req, rm, body, data)
 	}
+
 	headers := rm.Headers(ctx)
 	if headers != nil {
 		for key, val := range headers {
 			req.Response.Header().Set(key, val)
 		}
 	}
-	req.Response.WriteHeader(rm.Status(ctx))
+
+	if status == http.StatusOK && notModified(req) {
+		req.Response.Header().Del("Content-Type")
+		req.Response.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	req.Response.WriteHeader(status)
 	req.Response.Write(data)
 	return nil
 }
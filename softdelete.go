@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// SoftDeleter is implemented by DataSources which can mark models
+// as deleted instead of deleting them, see Resource.SoftDelete.
+// Soft deleted models are not found, unless the Query has IncludeDeleted.
+type SoftDeleter interface {
+	// SoftDelete marks the model of the id of the Query as deleted.
+	SoftDelete(context.Context) (context.Context, error)
+
+	// Restore unmarks the model of the id of the Query.
+	Restore(context.Context) (context.Context, error)
+}
+
+// Restore restores the soft deleted model of the id of the Query.
+func (r *Resource) Restore(ctx context.Context) (context.Context, error) {
+	sd, ok := r.Source.(SoftDeleter)
+	if !ok {
+		return ctx, NewError(http.StatusMethodNotAllowed, "Models cannot be restored")
+	}
+
+	q := QueryFrom(ctx)
+	q.IncludeDeleted = true
+	ctx = WithQuery(ctx, q)
+
+	c, err := sd.Restore(ctx)
+	if err != nil {
+		return c, err
+	}
+
+	return r.Source.FindOne(c)
+}
+
+func (r *Resource) HandleRestore(ctx context.Context, rp RequestParser) (context.Context, error) {
+	c, err := rp.ParseRequest(ctx, r.Req)
+	if err != nil {
+		return c, err
+	}
+
+	return r.Restore(c)
+}
+
+// remove deletes the model of the context,
+// or marks it as deleted in SoftDelete mode.
+func (r *Resource) remove(ctx context.Context) (context.Context, error) {
+	if sd, ok := r.Source.(SoftDeleter); ok && r.SoftDelete {
+		return sd.SoftDelete(ctx)
+	}
+	return r.Source.Delete(ctx)
+}
+
+// authorizeDeleted checks that queries including soft deleted models
+// are authorized by AuthorizeDeleted.
+func (r *Resource) authorizeDeleted(ctx context.Context) error {
+	if !r.SoftDelete || !QueryFrom(ctx).IncludeDeleted {
+		return nil
+	}
+
+	if r.AuthorizeDeleted != nil {
+		return r.AuthorizeDeleted(ctx, r.Req)
+	}
+
+	err := NewError(http.StatusForbidden, "Forbidden")
+	err.Code = "forbidden"
+	err.Detail = "include_deleted is not allowed"
+	err.Source = &ErrorSource{Parameter: "include_deleted"}
+	return err
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type trashedPet struct {
+	ID        string     `json:"id,omitempty"`
+	Name      string     `json:"name"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+var _ = Describe("SoftDelete", func() {
+	var (
+		api    *API
+		source *MemorySource
+	)
+
+	BeforeEach(func() {
+		source = NewMemorySource(trashedPet{})
+		source.Create(WithModel(context.Background(), &trashedPet{Name: "simba"}))
+		source.Create(WithModel(context.Background(), &trashedPet{Name: "nala"}))
+
+		authorize := func(ctx context.Context, r *Req) error {
+			if r.Request.Header.Get("X-Admin") != "true" {
+				return NewError(http.StatusForbidden, "Admins only")
+			}
+			return nil
+		}
+
+		api = New("")
+		api.AddResource("pets", source, ResourceModel(trashedPet{}), SoftDelete(authorize), Enable(ActionBulkDelete))
+	})
+
+	It("hides soft deleted models", func() {
+		Expect(serve(api, "DELETE", "/pets/1", "").Code).To(Equal(http.StatusNoContent))
+
+		Expect(serve(api, "GET", "/pets/1", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "PATCH", "/pets/1", `{"name":"kiara"}`).Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "DELETE", "/pets/1", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "GET", "/pets", "").Body.String()).To(MatchJSON(`[{"id":"2","name":"nala"}]`))
+
+		ctx, err := source.FindOne(WithQuery(context.Background(), Query{ID: "1", IncludeDeleted: true}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ResultFrom(ctx).Data.(*trashedPet).DeletedAt).ToNot(BeNil())
+	})
+
+	It("shows soft deleted models to authorized requests", func() {
+		serve(api, "DELETE", "/pets/1", "")
+
+		w := serve(api, "GET", "/pets?include_deleted=true", "")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		w = serve(api, "GET", "/pets?include_deleted=true", "", "X-Admin", "true")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"deleted_at"`))
+		Expect(w.Body.String()).To(ContainSubstring(`"nala"`))
+
+		Expect(serve(api, "GET", "/pets/1?include_deleted=true", "", "X-Admin", "true").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "GET", "/pets?include_deleted=maybe", "").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("restores soft deleted models", func() {
+		serve(api, "DELETE", "/pets?id=1,2", "")
+		Expect(serve(api, "GET", "/pets", "").Body.String()).To(MatchJSON(`[]`))
+
+		w := serve(api, "POST", "/pets/1/restore", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"id":"1","name":"simba"}`))
+
+		Expect(serve(api, "GET", "/pets/1", "").Code).To(Equal(http.StatusOK))
+		Expect(serve(api, "POST", "/pets/9/restore", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("forbids deleted models without an authorization", func() {
+		api = New("")
+		api.AddResource("pets", source, SoftDelete(nil))
+
+		w := serve(api, "GET", "/pets?include_deleted=true", "", "X-Admin", "true")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Body.String()).To(ContainSubstring(`"parameter":"include_deleted"`))
+	})
+
+	It("is only enabled on request", func() {
+		api = New("")
+		api.AddResource("pets", source)
+
+		Expect(serve(api, "DELETE", "/pets/1", "").Code).To(Equal(http.StatusNoContent))
+		Expect(serve(api, "GET", "/pets/1?include_deleted=true", "").Code).To(Equal(http.StatusNotFound))
+		Expect(serve(api, "POST", "/pets/1/restore", "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("requires a SoftDeleter", func() {
+		Expect(func() {
+			New("").AddResource("pets", &crudPetSource{}, SoftDelete(nil))
+		}).To(Panic())
+	})
+})
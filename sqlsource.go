@@ -0,0 +1,518 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// SQLSource is a DataSource storing models in a table of a database/sql database.
+//
+// Models are structs whose fields map to columns with `db:"column"` tags,
+// and to their snake-cased name otherwise. Fields tagged with `db:"-"` are ignored.
+// The id column is tagged with `db:"column,primary"`, or else named id.
+// Ids are generated by the database on Create when empty,
+// and read with RETURNING on PostgreSQL, or LastInsertId otherwise.
+// Fields are filtered and sorted by their JSON names, as with a MemorySource.
+//
+// Queries are parameterized. Missing rows are 404 Errors,
+// and unique constraint violations 409 Errors.
+type SQLSource struct {
+	DB    *sql.DB
+	Table string
+
+	// Dialect of the database, SQLite by default.
+	Dialect Dialect
+
+	// Placeholder returns the placeholder of the i-th parameter of a query,
+	// from 1. Defaults to DollarPlaceholder for PostgreSQL, and ? otherwise.
+	Placeholder func(i int) string
+
+	// IsConflict reports whether an error of the driver is a unique
+	// constraint violation. Defaults to IsUniqueViolation.
+	IsConflict func(error) bool
+
+	// Cursors makes FindAll return cursors of next pages
+	// instead of relying on offsets.
+	Cursors bool
+
+	typ     reflect.Type
+	id      sqlColumn
+	columns []sqlColumn
+	fields  map[string]sqlColumn // by JSON name
+}
+
+// Dialect is the SQL dialect of the database of a SQLSource.
+type Dialect int
+
+const (
+	SQLite Dialect = iota
+	PostgreSQL
+	MySQL
+)
+
+type sqlColumn struct {
+	name  string
+	index []int
+}
+
+// DollarPlaceholder numbers parameters as $1, $2...
+func DollarPlaceholder(i int) string {
+	return "$" + strconv.Itoa(i)
+}
+
+// IsUniqueViolation recognizes unique constraint violations
+// of SQLite, PostgreSQL and MySQL from their messages.
+func IsUniqueViolation(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") ||
+		strings.Contains(msg, "duplicate key value") ||
+		strings.Contains(msg, "Duplicate entry")
+}
+
+// NewSQLSource returns a SQLSource of models of the type of model, i.e. Pet{},
+// stored in a table.
+func NewSQLSource(db *sql.DB, table string, model interface{}) *SQLSource {
+	t := bodyType(model)
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("api: SQLSource expects a struct model, not %T", model))
+	}
+
+	s := &SQLSource{
+		DB:     db,
+		Table:  table,
+		typ:    t,
+		fields: map[string]sqlColumn{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, opts := parseJSONTag(f.Tag.Get("db"))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		c := sqlColumn{name: name, index: f.Index}
+		s.columns = append(s.columns, c)
+
+		if contains(strings.Split(opts, ","), "primary") || (s.id.index == nil && name == "id") {
+			s.id = c
+		}
+
+		jsonName, _ := parseJSONTag(f.Tag.Get("json"))
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		s.fields[jsonName] = c
+	}
+
+	if s.id.index == nil {
+		panic(fmt.Sprintf("api: SQLSource model %s has no id column", t))
+	}
+
+	return s
+}
+
+// FindOne finds the row of the id of the Query.
+func (s *SQLSource) FindOne(ctx context.Context) (context.Context, error) {
+	id := IDFrom(ctx)
+	idValue, err := s.idValue(id)
+	if err != nil {
+		return ctx, s.notFound(id)
+	}
+
+	m := reflect.New(s.typ).Elem()
+	query := "SELECT " + s.columnList() + " FROM " + s.Table + " WHERE " + s.id.name + " = " + s.placeholder(1)
+	if err := s.DB.QueryRowContext(ctx, query, idValue).Scan(s.pointers(m)...); err != nil {
+		if err == sql.ErrNoRows {
+			return ctx, s.notFound(id)
+		}
+		return ctx, err
+	}
+
+	return WithResult(ctx, Result{Data: m.Addr().Interface()}), nil
+}
+
+// FindAll finds the rows matching the filters of the Query,
+// in the order of its sort, or else by id,
+// and returns the page of the Query with the Total of rows.
+func (s *SQLSource) FindAll(ctx context.Context) (context.Context, error) {
+	q := QueryFrom(ctx)
+
+	where, args, err := s.where(q.Filters)
+	if err != nil {
+		return ctx, err
+	}
+
+	var total int
+	if err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+s.Table+where, args...).Scan(&total); err != nil {
+		return ctx, err
+	}
+
+	order, err := s.orderBy(q.Sort)
+	if err != nil {
+		return ctx, err
+	}
+
+	offset := q.Page.Offset
+	if q.Page.Cursor != "" {
+		// Cursors of a SQLSource are offsets, made opaque by EncodeCursor.
+		offset, err = strconv.Atoi(q.Page.Cursor)
+		if err != nil || offset < 0 {
+			return ctx, queryError("page[cursor]", "is invalid")
+		}
+	}
+
+	page, args := s.page(args, q.Page.Limit, offset)
+	query := "SELECT " + s.columnList() + " FROM " + s.Table + where + order + page
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return ctx, err
+	}
+	defer rows.Close()
+
+	data := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(s.typ)), 0, q.Page.Limit)
+	for rows.Next() {
+		m := reflect.New(s.typ)
+		if err := rows.Scan(s.pointers(m.Elem())...); err != nil {
+			return ctx, err
+		}
+		data = reflect.Append(data, m)
+	}
+	if err := rows.Err(); err != nil {
+		return ctx, err
+	}
+
+	result := Result{Data: data.Interface(), Total: &total}
+	if end := offset + data.Len(); s.Cursors && data.Len() > 0 && end < total {
+		result.NextCursor = strconv.Itoa(end)
+	}
+
+	return WithResult(ctx, result), nil
+}
+
+// Create inserts the model of the context, see WithModel,
+// and returns a context with its id.
+func (s *SQLSource) Create(ctx context.Context) (context.Context, error) {
+	m, err := s.model(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	var (
+		columns []string
+		values  []string
+		args    []interface{}
+	)
+	generated := isZero(m.FieldByIndex(s.id.index))
+	for _, c := range s.columns {
+		if generated && c.name == s.id.name {
+			continue
+		}
+		columns = append(columns, c.name)
+		args = append(args, m.FieldByIndex(c.index).Interface())
+		values = append(values, s.placeholder(len(args)))
+	}
+
+	query := "INSERT INTO " + s.Table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ")"
+
+	// PostgreSQL drivers do not support LastInsertId.
+	if generated && s.Dialect == PostgreSQL {
+		id := m.FieldByIndex(s.id.index).Addr().Interface()
+		if err := s.DB.QueryRowContext(ctx, query+" RETURNING "+s.id.name, args...).Scan(id); err != nil {
+			return ctx, s.mapError(err)
+		}
+		return WithID(ctx, valueString(indirect(m.FieldByIndex(s.id.index)))), nil
+	}
+
+	res, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return ctx, s.mapError(err)
+	}
+
+	if generated {
+		id, err := res.LastInsertId()
+		if err != nil {
+			return ctx, err
+		}
+		if err := setValue(m.FieldByIndex(s.id.index), strconv.FormatInt(id, 10)); err != nil {
+			return ctx, err
+		}
+	}
+
+	return WithID(ctx, valueString(indirect(m.FieldByIndex(s.id.index)))), nil
+}
+
+// Update updates the row of the id of the Query
+// with the model of the context, see WithModel.
+func (s *SQLSource) Update(ctx context.Context) (context.Context, error) {
+	m, err := s.model(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	id := IDFrom(ctx)
+	idValue, err := s.idValue(id)
+	if err != nil {
+		return ctx, s.notFound(id)
+	}
+
+	var (
+		sets []string
+		args []interface{}
+	)
+	for _, c := range s.columns {
+		if c.name == s.id.name {
+			continue
+		}
+		args = append(args, m.FieldByIndex(c.index).Interface())
+		sets = append(sets, c.name+" = "+s.placeholder(len(args)))
+	}
+	args = append(args, idValue)
+
+	query := "UPDATE " + s.Table + " SET " + strings.Join(sets, ", ") + " WHERE " + s.id.name + " = " + s.placeholder(len(args))
+	res, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return ctx, s.mapError(err)
+	}
+
+	return ctx, s.checkAffected(ctx, res, id, idValue)
+}
+
+// Delete deletes the row of the id of the Query.
+func (s *SQLSource) Delete(ctx context.Context) (context.Context, error) {
+	id := IDFrom(ctx)
+	idValue, err := s.idValue(id)
+	if err != nil {
+		return ctx, s.notFound(id)
+	}
+
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM "+s.Table+" WHERE "+s.id.name+" = "+s.placeholder(1), idValue)
+	if err != nil {
+		return ctx, s.mapError(err)
+	}
+
+	return ctx, s.checkAffected(ctx, res, id, idValue)
+}
+
+// where translates filters into a WHERE clause.
+func (s *SQLSource) where(filters []Filter) (string, []interface{}, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	for _, f := range filters {
+		param := "filter[" + f.Field + "][" + string(f.Op) + "]"
+		c, ok := s.fields[f.Field]
+		if !ok {
+			return "", nil, queryError(param, "has an unknown field "+f.Field)
+		}
+		t := s.typ.FieldByIndex(c.index).Type
+
+		if f.Op == OpNull {
+			if f.Values[0] == "true" {
+				conditions = append(conditions, c.name+" IS NULL")
+			} else {
+				conditions = append(conditions, c.name+" IS NOT NULL")
+			}
+			continue
+		}
+
+		var placeholders []string
+		for _, value := range f.Values {
+			arg := interface{}(value)
+			if f.Op != OpLike {
+				v, err := sqlValue(t, value)
+				if err != nil {
+					return "", nil, queryError(param, err.Error())
+				}
+				arg = v
+			}
+			args = append(args, arg)
+			placeholders = append(placeholders, s.placeholder(len(args)))
+		}
+
+		switch f.Op {
+		case OpEq:
+			conditions = append(conditions, c.name+" = "+placeholders[0])
+		case OpNe:
+			conditions = append(conditions, c.name+" <> "+placeholders[0])
+		case OpLt:
+			conditions = append(conditions, c.name+" < "+placeholders[0])
+		case OpGt:
+			conditions = append(conditions, c.name+" > "+placeholders[0])
+		case OpLike:
+			conditions = append(conditions, c.name+" LIKE "+placeholders[0])
+		case OpIn:
+			conditions = append(conditions, c.name+" IN ("+strings.Join(placeholders, ", ")+")")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// orderBy translates sort fields into an ORDER BY clause, ending with the id.
+func (s *SQLSource) orderBy(fields []SortField) (string, error) {
+	var order []string
+	for _, f := range fields {
+		c, ok := s.fields[f.Field]
+		if !ok {
+			return "", queryError("sort", "has an unknown field "+f.Field)
+		}
+		if f.Desc {
+			order = append(order, c.name+" DESC")
+		} else {
+			order = append(order, c.name+" ASC")
+		}
+	}
+	order = append(order, s.id.name+" ASC")
+	return " ORDER BY " + strings.Join(order, ", "), nil
+}
+
+// page translates a limit and an offset into LIMIT and OFFSET clauses.
+func (s *SQLSource) page(args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit > 0 {
+		args = append(args, limit, offset)
+		return " LIMIT " + s.placeholder(len(args)-1) + " OFFSET " + s.placeholder(len(args)), args
+	}
+	if offset == 0 {
+		return "", args
+	}
+
+	args = append(args, offset)
+	switch s.Dialect {
+	case PostgreSQL:
+		return " OFFSET " + s.placeholder(len(args)), args
+	case MySQL:
+		// MySQL requires a limit with an offset: the documented way
+		// to get every row is the largest unsigned 64-bit integer.
+		return " LIMIT 18446744073709551615 OFFSET " + s.placeholder(len(args)), args
+	default:
+		return " LIMIT -1 OFFSET " + s.placeholder(len(args)), args
+	}
+}
+
+// sqlValue converts the string representation of a value
+// to an argument for a column of type t.
+func sqlValue(t reflect.Type, s string) (interface{}, error) {
+	v := reflect.New(t).Elem()
+	if err := setValue(v, s); err != nil {
+		return nil, err
+	}
+	return indirect(v).Interface(), nil
+}
+
+func (s *SQLSource) idValue(id string) (interface{}, error) {
+	return sqlValue(s.typ.FieldByIndex(s.id.index).Type, id)
+}
+
+// model returns the addressable model of the context.
+func (s *SQLSource) model(ctx context.Context) (reflect.Value, error) {
+	v := reflect.ValueOf(ModelFrom(ctx))
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type() == s.typ {
+		return v.Elem(), nil
+	}
+	if v.IsValid() && v.Type() == s.typ {
+		m := reflect.New(s.typ).Elem()
+		m.Set(v)
+		return m, nil
+	}
+	return reflect.Value{}, NewError(http.StatusInternalServerError, fmt.Sprintf("api: SQLSource expects a %s model, not %T", s.typ, ModelFrom(ctx)))
+}
+
+func (s *SQLSource) columnList() string {
+	names := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// pointers returns pointers to the fields of a model, to scan a row.
+func (s *SQLSource) pointers(m reflect.Value) []interface{} {
+	ptrs := make([]interface{}, len(s.columns))
+	for i, c := range s.columns {
+		ptrs[i] = m.FieldByIndex(c.index).Addr().Interface()
+	}
+	return ptrs
+}
+
+func (s *SQLSource) placeholder(i int) string {
+	if s.Placeholder != nil {
+		return s.Placeholder(i)
+	}
+	if s.Dialect == PostgreSQL {
+		return DollarPlaceholder(i)
+	}
+	return "?"
+}
+
+// checkAffected returns a 404 Error when no row was affected
+// because the row of the id is missing.
+// MySQL does not count the rows an UPDATE leaves unchanged as affected.
+func (s *SQLSource) checkAffected(ctx context.Context, res sql.Result, id string, idValue interface{}) error {
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+
+	var found int
+	err = s.DB.QueryRowContext(ctx, "SELECT 1 FROM "+s.Table+" WHERE "+s.id.name+" = "+s.placeholder(1), idValue).Scan(&found)
+	if err == sql.ErrNoRows {
+		return s.notFound(id)
+	}
+	return err
+}
+
+// mapError maps unique constraint violations to 409 Errors.
+func (s *SQLSource) mapError(err error) error {
+	isConflict := s.IsConflict
+	if isConflict == nil {
+		isConflict = IsUniqueViolation
+	}
+	if isConflict(err) {
+		return conflictError(fmt.Sprintf("%s conflicts with an existing one", s.typ.Name()))
+	}
+	return err
+}
+
+func (s *SQLSource) notFound(id string) *Error {
+	return notFoundError(s.typ, id)
+}
+
+// snakeCase converts a field name to a column name, i.e. OwnerID to owner_id.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		lower := strings.ToLower(string(r))
+		if i > 0 && lower != string(r) {
+			prevLower := strings.ToLower(string(runes[i-1])) == string(runes[i-1])
+			nextLower := i+1 < len(runes) && strings.ToLower(string(runes[i+1])) == string(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteString(lower)
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+type sqlPet struct {
+	ID      int64     `json:"id" db:"id,primary"`
+	Name    string    `json:"name" validate:"required"`
+	Age     int       `json:"age"`
+	Born    time.Time `json:"born"`
+	OwnerID *string   `json:"owner"`
+	Secret  string    `json:"-" db:"-"`
+}
+
+// unchangedResult is the result of a MySQL UPDATE leaving its row unchanged.
+type unchangedResult struct{}
+
+func (unchangedResult) LastInsertId() (int64, error) { return 0, nil }
+func (unchangedResult) RowsAffected() (int64, error) { return 0, nil }
+
+var _ = Describe("SQLSource", func() {
+	var (
+		db     *sql.DB
+		source *SQLSource
+	)
+
+	create := func(p sqlPet) string {
+		ctx, err := source.Create(WithModel(context.Background(), &p))
+		Expect(err).ToNot(HaveOccurred())
+		return IDFrom(ctx)
+	}
+
+	findAll := func(q Query) ([]string, int, error) {
+		ctx, err := source.FindAll(WithQuery(context.Background(), q))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var names []string
+		for _, p := range ResultFrom(ctx).Data.([]*sqlPet) {
+			names = append(names, p.Name)
+		}
+		return names, *ResultFrom(ctx).Total, nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		db, err = sql.Open("sqlite3", ":memory:")
+		Expect(err).ToNot(HaveOccurred())
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec(`CREATE TABLE pets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			age INTEGER NOT NULL,
+			born DATETIME NOT NULL,
+			owner_id TEXT
+		)`)
+		Expect(err).ToNot(HaveOccurred())
+
+		source = NewSQLSource(db, "pets", sqlPet{})
+
+		mufasa := "mufasa"
+		create(sqlPet{Name: "simba", Age: 3, Born: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), OwnerID: &mufasa})
+		create(sqlPet{Name: "nala", Age: 3, Born: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)})
+		create(sqlPet{Name: "kiara", Age: 1, Born: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("maps models to columns", func() {
+		Expect(source.columnList()).To(Equal("id, name, age, born, owner_id"))
+		Expect(snakeCase("OwnerID")).To(Equal("owner_id"))
+		Expect(snakeCase("HTTPStatusCode")).To(Equal("http_status_code"))
+	})
+
+	It("supports the lifecycle of a Resource", func() {
+		ctx, err := source.FindOne(WithID(context.Background(), "2"))
+		Expect(err).ToNot(HaveOccurred())
+
+		nala := ResultFrom(ctx).Data.(*sqlPet)
+		Expect(nala.Name).To(Equal("nala"))
+		Expect(nala.Born.Equal(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))).To(BeTrue())
+		Expect(nala.OwnerID).To(BeNil())
+
+		nala.Age = 4
+		_, err = source.Update(WithModel(WithID(context.Background(), "2"), nala))
+		Expect(err).ToNot(HaveOccurred())
+		ctx, _ = source.FindOne(WithID(context.Background(), "2"))
+		Expect(ResultFrom(ctx).Data.(*sqlPet).Age).To(Equal(4))
+
+		_, err = source.Delete(WithID(context.Background(), "2"))
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, id := range []string{"2", "nala"} {
+			_, err = source.FindOne(WithID(context.Background(), id))
+			Expect(err.(*Error).Status).To(Equal("404"))
+			_, err = source.Update(WithModel(WithID(context.Background(), id), nala))
+			Expect(err.(*Error).Status).To(Equal("404"))
+			_, err = source.Delete(WithID(context.Background(), id))
+			Expect(err.(*Error).Status).To(Equal("404"))
+		}
+
+		Expect(create(sqlPet{Name: "rafiki"})).To(Equal("4"))
+		Expect(create(sqlPet{ID: 10, Name: "zazu"})).To(Equal("10"))
+
+		_, err = source.Create(WithModel(context.Background(), &sqlPet{Name: "zazu"}))
+		Expect(err.(*Error).Status).To(Equal("409"))
+		_, err = source.Update(WithModel(WithID(context.Background(), "10"), &sqlPet{Name: "simba"}))
+		Expect(err.(*Error).Status).To(Equal("409"))
+	})
+
+	It("only reports rows which are missing as not found", func() {
+		Expect(source.checkAffected(context.Background(), unchangedResult{}, "1", int64(1))).To(Succeed())
+
+		err := source.checkAffected(context.Background(), unchangedResult{}, "9", int64(9))
+		Expect(err.(*Error).Status).To(Equal("404"))
+	})
+
+	It("reads primary keys among other tag options", func() {
+		type sqlTag struct {
+			Key  int64  `db:"key,primary,omitempty"`
+			ID   string `db:"id"`
+			Name string
+		}
+		Expect(NewSQLSource(db, "tags", sqlTag{}).id.name).To(Equal("key"))
+	})
+
+	It("filters, sorts and paginates", func() {
+		names, total, err := findAll(Query{Sort: []SortField{{Field: "age", Desc: true}, {Field: "name"}}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(Equal([]string{"nala", "simba", "kiara"}))
+		Expect(total).To(Equal(3))
+
+		for _, c := range []struct {
+			filter   Filter
+			expected []string
+		}{
+			{Filter{Field: "age", Op: OpEq, Values: []string{"3"}}, []string{"simba", "nala"}},
+			{Filter{Field: "age", Op: OpNe, Values: []string{"3"}}, []string{"kiara"}},
+			{Filter{Field: "age", Op: OpLt, Values: []string{"3"}}, []string{"kiara"}},
+			{Filter{Field: "born", Op: OpGt, Values: []string{"2018-06-01T00:00:00Z"}}, []string{"simba", "kiara"}},
+			{Filter{Field: "name", Op: OpIn, Values: []string{"kiara", "nala"}}, []string{"nala", "kiara"}},
+			{Filter{Field: "name", Op: OpLike, Values: []string{"n%"}}, []string{"nala"}},
+			{Filter{Field: "owner", Op: OpNull, Values: []string{"true"}}, []string{"nala", "kiara"}},
+			{Filter{Field: "owner", Op: OpNull, Values: []string{"false"}}, []string{"simba"}},
+		} {
+			names, total, err := findAll(Query{Filters: []Filter{c.filter}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal(c.expected), "%v", c.filter)
+			Expect(total).To(Equal(len(c.expected)))
+		}
+
+		names, total, _ = findAll(Query{Page: Page{Offset: 1, Limit: 1}})
+		Expect(names).To(Equal([]string{"nala"}))
+		Expect(total).To(Equal(3))
+
+		names, _, _ = findAll(Query{Page: Page{Offset: 1}})
+		Expect(names).To(Equal([]string{"nala", "kiara"}))
+
+		source.Cursors = true
+		ctx, _ := source.FindAll(WithQuery(context.Background(), Query{Page: Page{Limit: 2}}))
+		Expect(ResultFrom(ctx).NextCursor).To(Equal("2"))
+
+		names, _, _ = findAll(Query{Page: Page{Limit: 2, Cursor: "2"}})
+		Expect(names).To(Equal([]string{"kiara"}))
+	})
+
+	It("paginates in the dialect of the database", func() {
+		for dialect, expected := range map[Dialect]string{
+			SQLite:     " LIMIT -1 OFFSET ?",
+			PostgreSQL: " OFFSET $1",
+			MySQL:      " LIMIT 18446744073709551615 OFFSET ?",
+		} {
+			source.Dialect = dialect
+			clause, args := source.page(nil, 0, 10)
+			Expect(clause).To(Equal(expected))
+			Expect(args).To(Equal([]interface{}{10}))
+		}
+
+		source.Dialect = PostgreSQL
+		clause, args := source.page([]interface{}{"simba"}, 5, 10)
+		Expect(clause).To(Equal(" LIMIT $2 OFFSET $3"))
+		Expect(args).To(Equal([]interface{}{"simba", 5, 10}))
+	})
+
+	It("returns generated ids with RETURNING on PostgreSQL", func() {
+		// SQLite supports RETURNING too, with ? placeholders.
+		source.Dialect = PostgreSQL
+		source.Placeholder = func(int) string { return "?" }
+
+		p := &sqlPet{Name: "kovu", Born: time.Now()}
+		ctx, err := source.Create(WithModel(context.Background(), p))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(IDFrom(ctx)).To(Equal("4"))
+		Expect(p.ID).To(Equal(int64(4)))
+
+		_, err = source.Create(WithModel(context.Background(), &sqlPet{Name: "kovu"}))
+		Expect(err.(*Error).Status).To(Equal("409"))
+	})
+
+	It("reports unknown fields and invalid values", func() {
+		_, _, err := findAll(Query{Filters: []Filter{{Field: "secret", Op: OpEq, Values: []string{"1"}}}})
+		Expect(err.(*Error).Status).To(Equal("400"))
+
+		_, _, err = findAll(Query{Filters: []Filter{{Field: "age", Op: OpGt, Values: []string{"old"}}}})
+		Expect(err.(*Error).Source.Parameter).To(Equal("filter[age][gt]"))
+
+		_, _, err = findAll(Query{Sort: []SortField{{Field: "name; DROP TABLE pets"}}})
+		Expect(err.(*Error).Status).To(Equal("400"))
+	})
+
+	It("serves a Resource", func() {
+		api := New("")
+		api.AddResource("pets", source, ResourceModel(sqlPet{}))
+
+		w := serve(api, "POST", "/pets", `{"name":"rafiki","age":9,"born":"2000-01-01T00:00:00Z"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Header().Get("Location")).To(Equal("/pets/4"))
+
+		w = serve(api, "GET", "/pets?filter[name][like]=%25i%25&sort=-age&page[limit]=2", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("X-Total-Count")).To(Equal("3"))
+		Expect(w.Body.String()).To(ContainSubstring(`"name":"rafiki"`))
+
+		w = serve(api, "POST", "/pets", `{"name":"simba","born":"2000-01-01T00:00:00Z"}`)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+	})
+})
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pborman/uuid"
+)
+
+// Supported rules of the "validate" tag, separated by commas:
+// 	 - "required" the value must not be the zero value
+// 	 - "omitempty" skip the other rules when the value is the zero value
+// 	 - "min=n", "max=n" bounds of numbers, or of the length of strings, slices and maps
+// 	 - "len=n" exact length of strings, slices and maps
+// 	 - "oneof=a b c" allowed values, separated by spaces
+// 	 - "email", "url", "uuid" formats of strings
+// 	 - "regexp=expr" regular expression strings must match (without commas)
+// 	 - "dive" apply the following rules to each element of a slice or map
+//
+// Nested structs, and structs in slices and maps, are validated as well.
+
+// Validator is implemented by types validating themselves.
+// Validate is called after the rules of the type's fields were checked.
+// Errors, or *Error, whose Path is a JSON pointer relative to the value
+// are reported at the right place.
+type Validator interface {
+	Validate() error
+}
+
+// Validate validates a value with the rules of its "validate" tags
+// and the Validator interface.
+// It returns Errors with one 422 Error per invalid field,
+// its Path being the JSON pointer of the field.
+func Validate(v interface{}) error {
+	errs := Errors{}
+	validateValue(reflect.ValueOf(v), "", &errs)
+
+	if len(errs.Err) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, pointer string, errs *Errors) {
+	if !v.IsValid() {
+		return
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+
+			name, _ := parseJSONTag(f.Tag.Get("json"))
+			if name == "-" {
+				continue
+			}
+
+			fv := v.Field(i)
+			if f.Anonymous && name == "" {
+				validateValue(fv, pointer, errs)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			p := pointer + "/" + escapePointer(name)
+			if validateRules(fv, p, splitRules(f.Tag.Get("validate")), errs) {
+				validateValue(fv, p, errs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), pointer+"/"+strconv.Itoa(i), errs)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			validateValue(v.MapIndex(k), pointer+"/"+escapePointer(valueString(k)), errs)
+		}
+	}
+
+	callValidator(v, pointer, errs)
+}
+
+// validateRules checks the rules on a value.
+// It returns false if the value is invalid and should not be inspected further.
+func validateRules(v reflect.Value, pointer string, rules []string, errs *Errors) bool {
+	for i, rule := range rules {
+		name, arg := rule, ""
+		if j := strings.Index(rule, "="); j >= 0 {
+			name, arg = rule[:j], rule[j+1:]
+		}
+
+		switch name {
+		case "omitempty":
+			if isZero(v) {
+				return false
+			}
+			continue
+		case "dive":
+			v = indirect(v)
+			switch v.Kind() {
+			case reflect.Slice, reflect.Array:
+				for k := 0; k < v.Len(); k++ {
+					p := pointer + "/" + strconv.Itoa(k)
+					if validateRules(v.Index(k), p, rules[i+1:], errs) {
+						validateValue(v.Index(k), p, errs)
+					}
+				}
+			case reflect.Map:
+				for _, k := range v.MapKeys() {
+					p := pointer + "/" + escapePointer(valueString(k))
+					if validateRules(v.MapIndex(k), p, rules[i+1:], errs) {
+						validateValue(v.MapIndex(k), p, errs)
+					}
+				}
+			}
+			return false
+		}
+
+		if detail := checkRule(v, name, arg); detail != "" {
+			title := "Invalid value"
+			if name == "required" {
+				title = "Missing value"
+			}
+
+			errs.Add(&Error{
+				Status: strconv.Itoa(http.StatusUnprocessableEntity),
+				Code:   name,
+				Title:  title,
+				Detail: detail,
+				Path:   pointer,
+				Source: &ErrorSource{Pointer: pointer},
+			})
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkRule checks a rule on a value and describes why it failed,
+// or returns an empty string.
+func checkRule(v reflect.Value, rule, arg string) string {
+	if rule == "required" {
+		if isZero(v) {
+			return "is required"
+		}
+		return ""
+	}
+
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch rule {
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("api: invalid %s rule %q", rule, arg))
+		}
+
+		size, isLength := measure(v)
+		unit := ""
+		if isLength {
+			unit = " long"
+			if v.Kind() == reflect.String {
+				unit = " characters long"
+			}
+		}
+
+		switch {
+		case rule == "min" && size < n:
+			return fmt.Sprintf("must be at least %v%s", n, unit)
+		case rule == "max" && size > n:
+			return fmt.Sprintf("must be at most %v%s", n, unit)
+		case rule == "len" && size != n:
+			return fmt.Sprintf("must be exactly %v%s", n, unit)
+		}
+	case "oneof":
+		values := strings.Fields(arg)
+		if !contains(values, valueString(v)) {
+			return "must be one of " + strings.Join(values, ", ")
+		}
+	case "email":
+		a, err := mail.ParseAddress(v.String())
+		if err != nil || a.Address != v.String() {
+			return "must be an email address"
+		}
+	case "url":
+		u, err := url.ParseRequestURI(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an absolute url"
+		}
+	case "uuid":
+		if uuid.Parse(v.String()) == nil {
+			return "must be a uuid"
+		}
+	case "regexp":
+		if !compileRegexp(arg).MatchString(v.String()) {
+			return "must match " + arg
+		}
+	default:
+		panic(fmt.Sprintf("api: unknown validation rule %q", rule))
+	}
+
+	return ""
+}
+
+// callValidator calls the Validate method of a value, if any,
+// and reports its errors relative to the pointer of the value.
+func callValidator(v reflect.Value, pointer string, errs *Errors) {
+	// Values of unexported fields cannot be used.
+	if !v.CanInterface() {
+		return
+	}
+
+	var validator Validator
+	if v.CanAddr() {
+		validator, _ = v.Addr().Interface().(Validator)
+	}
+	if validator == nil {
+		validator, _ = v.Interface().(Validator)
+	}
+	if validator == nil {
+		return
+	}
+
+	err := validator.Validate()
+	if err == nil {
+		return
+	}
+
+	var found []*Error
+	switch e := err.(type) {
+	case Errors:
+		found = e.Err
+	case *Errors:
+		found = e.Err
+	case *Error:
+		found = []*Error{e}
+	default:
+		found = []*Error{{
+			Status: strconv.Itoa(http.StatusUnprocessableEntity),
+			Title:  "Invalid value",
+			Detail: err.Error(),
+		}}
+	}
+
+	// Validators may return shared errors: annotate copies.
+	for _, f := range found {
+		e := *f
+		e.Path = pointer + e.Path
+		e.Source = &ErrorSource{Pointer: e.Path}
+		if e.Status == "" {
+			e.Status = strconv.Itoa(http.StatusUnprocessableEntity)
+		}
+		errs.Add(&e)
+	}
+}
+
+// measure returns the value of a number,
+// or the length of a string, slice or map.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	panic(fmt.Sprintf("api: cannot measure a %s", v.Type()))
+}
+
+// valueString formats a basic value, even from an unexported field.
+func valueString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+// escapePointer escapes a JSON pointer token (RFC 6901).
+func escapePointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+var regexps sync.Map
+
+// compileRegexp compiles a regular expression once.
+// It panics on invalid expressions, which are programming errors.
+func compileRegexp(expr string) *regexp.Regexp {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	re := regexp.MustCompile(expr)
+	regexps.Store(expr, re)
+	return re
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type vaccine struct {
+	Name string `json:"name" validate:"required"`
+	Year int    `json:"year" validate:"min=1990,max=2030"`
+}
+
+type checkedPet struct {
+	ID       string             `json:"id" validate:"omitempty,uuid"`
+	Name     string             `json:"name" validate:"required,min=2,max=10"`
+	Kind     string             `json:"kind" validate:"oneof=cat dog"`
+	Code     string             `json:"code,omitempty" validate:"omitempty,len=3,regexp=^[A-Z]+$"`
+	Email    string             `json:"email,omitempty" validate:"omitempty,email"`
+	Website  string             `json:"website,omitempty" validate:"omitempty,url"`
+	Tags     []string           `json:"tags" validate:"max=2,dive,min=3"`
+	Notes    map[string]string  `json:"notes" validate:"dive,required"`
+	Vaccines []vaccine          `json:"vaccines"`
+	Owners   map[string]vaccine `json:"owners"`
+}
+
+func (p checkedPet) Validate() error {
+	if p.Kind == "cat" && p.Name == "rex" {
+		return &Error{Title: "Invalid name", Detail: "rex is a dog name", Path: "/name"}
+	}
+	return nil
+}
+
+type positive int
+
+func (p positive) Validate() error {
+	if p <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+var errBlank = &Error{Title: "Blank", Detail: "must not be blank"}
+
+type blank string
+
+func (b blank) Validate() error {
+	if b == "" {
+		return errBlank
+	}
+	return nil
+}
+
+var _ = Describe("Validate", func() {
+	valid := func() checkedPet {
+		return checkedPet{
+			ID:       "4f3a4bb2-1b6f-4a0d-9a3c-3c8d2f1b6a1e",
+			Name:     "simba",
+			Kind:     "cat",
+			Code:     "ABC",
+			Email:    "simba@example.com",
+			Website:  "https://example.com/simba",
+			Tags:     []string{"lion", "king"},
+			Notes:    map[string]string{"food": "meat"},
+			Vaccines: []vaccine{{Name: "rabies", Year: 2015}},
+		}
+	}
+
+	It("accepts valid values", func() {
+		p := valid()
+		Expect(Validate(&p)).To(Succeed())
+	})
+
+	It("reports one error per invalid field with its JSON pointer", func() {
+		p := valid()
+		p.ID = "abc"
+		p.Name = ""
+		p.Kind = "lion"
+		p.Code = "abcd"
+		p.Email = "simba"
+		p.Website = "example.com"
+
+		err := Validate(p)
+		Expect(err).To(HaveOccurred())
+
+		errs := err.(Errors)
+		Expect(errs.HTTPStatus()).To(Equal(http.StatusUnprocessableEntity))
+
+		paths := []string{}
+		codes := []string{}
+		for _, e := range errs.Err {
+			paths = append(paths, e.Path)
+			codes = append(codes, e.Code)
+			Expect(e.Source.Pointer).To(Equal(e.Path))
+		}
+		Expect(paths).To(Equal([]string{"/id", "/name", "/kind", "/code", "/email", "/website"}))
+		Expect(codes).To(Equal([]string{"uuid", "required", "oneof", "len", "email", "url"}))
+	})
+
+	It("dives into slices, maps and nested structs", func() {
+		p := valid()
+		p.Tags = []string{"lion", "ki"}
+		p.Notes = map[string]string{"a/b": ""}
+		p.Vaccines = []vaccine{{Name: "rabies", Year: 2015}, {Year: 1980}}
+		p.Owners = map[string]vaccine{"nala": {Name: "x", Year: 2050}}
+
+		errs := Validate(&p).(Errors)
+
+		paths := []string{}
+		for _, e := range errs.Err {
+			paths = append(paths, e.Path)
+		}
+		Expect(paths).To(Equal([]string{
+			"/tags/1",
+			"/notes/a~1b",
+			"/vaccines/1/name",
+			"/vaccines/1/year",
+			"/owners/nala/year",
+		}))
+
+		p = valid()
+		p.Tags = []string{"lion", "king", "cub"}
+		errs = Validate(&p).(Errors)
+		Expect(errs.Err).To(HaveLen(1))
+		Expect(errs.Err[0].Detail).To(Equal("must be at most 2 long"))
+	})
+
+	It("calls Validator implementations", func() {
+		p := valid()
+		p.Name = "rex"
+
+		errs := Validate(p).(Errors)
+		Expect(errs.Err).To(HaveLen(1))
+		Expect(errs.Err[0].Path).To(Equal("/name"))
+		Expect(errs.Err[0].Status).To(Equal("422"))
+
+		s := struct {
+			Count positive `json:"count"`
+		}{}
+		errs = Validate(s).(Errors)
+		Expect(errs.Err[0].Path).To(Equal("/count"))
+		Expect(errs.Err[0].Detail).To(Equal("must be positive"))
+	})
+
+	It("does not modify the errors of Validator implementations", func() {
+		s := struct {
+			Nick blank `json:"nick"`
+		}{}
+
+		for i := 0; i < 2; i++ {
+			errs := Validate(s).(Errors)
+			Expect(errs.Err[0].Path).To(Equal("/nick"))
+		}
+		Expect(errBlank.Path).To(BeEmpty())
+		Expect(errBlank.Source).To(BeNil())
+	})
+})